@@ -8,13 +8,16 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // timezones of scheduled posts must resolve in minimal images
 
 	"github.com/gin-gonic/gin"
 	"github.com/think-root/bluesky-connector/internal/client"
 	"github.com/think-root/bluesky-connector/internal/config"
 	"github.com/think-root/bluesky-connector/internal/handlers"
+	"github.com/think-root/bluesky-connector/internal/idempotency"
 	"github.com/think-root/bluesky-connector/internal/logger"
 	"github.com/think-root/bluesky-connector/internal/middleware"
+	"github.com/think-root/bluesky-connector/internal/scheduler"
 )
 
 func main() {
@@ -33,14 +36,29 @@ func main() {
 		logger.Fatalf("Configuration validation failed: %v", err)
 	}
 
-	// Initialize Bluesky client
-	blueSkyClient := client.NewBlueSkyClient(cfg)
+	// Initialize a Bluesky client for every account
+	accounts := client.NewRegistry(cfg)
 
-	// Test authentication
-	if err := blueSkyClient.Authenticate(); err != nil {
+	// Test authentication. Only the default account is required to log in;
+	// the others retry on their first request
+	if err := accounts.AuthenticateAll()[cfg.Bluesky.DefaultAccount]; err != nil {
 		logger.Fatalf("Failed to authenticate with Bluesky: %v", err)
 	}
 
+	// Load the queue of scheduled posts and start publishing them
+	store, err := scheduler.NewStore(cfg.Scheduler.QueuePath)
+	if err != nil {
+		logger.Fatalf("Failed to load scheduled posts: %v", err)
+	}
+	postScheduler := scheduler.New(store, accounts, cfg.Scheduler.Interval)
+	postScheduler.Start()
+
+	// Load the responses kept for idempotent retries
+	idempotencyStore, err := idempotency.NewStore(cfg.Idempotency.StorePath, cfg.Idempotency.TTL)
+	if err != nil {
+		logger.Fatalf("Failed to load idempotency keys: %v", err)
+	}
+
 	// Set Gin mode
 	if cfg.Log.Level == "debug" {
 		gin.SetMode(gin.DebugMode)
@@ -51,13 +69,18 @@ func main() {
 	// Create Gin router
 	router := gin.New()
 
+	// Post URIs are passed as single percent-encoded path segments, so route
+	// on the raw path to keep their encoded slashes from splitting them
+	router.UseRawPath = true
+	router.UnescapePathValues = true
+
 	// Add middleware
 	router.Use(middleware.LoggingMiddleware())
 	router.Use(middleware.CORSMiddleware())
 	router.Use(gin.Recovery())
 
 	// Initialize handlers
-	postHandler := handlers.NewPostHandler(blueSkyClient)
+	postHandler := handlers.NewPostHandler(accounts, postScheduler)
 
 	// Health check route (no authentication required)
 	router.GET("/bluesky/api/health", postHandler.HealthCheck)
@@ -66,8 +89,26 @@ func main() {
 	api := router.Group("/bluesky/api")
 	api.Use(middleware.APIKeyMiddleware(cfg))
 	{
-		api.POST("/posts/create", postHandler.CreatePost)
+		idempotent := middleware.IdempotencyMiddleware(idempotencyStore)
+		api.POST("/posts/create", idempotent, postHandler.CreatePost)
+		api.POST("/posts/thread", idempotent, postHandler.CreateThread)
+		api.POST("/posts/preview", postHandler.PreviewPost)
+		api.GET("/posts/:uri", postHandler.GetPostStatus)
+		api.PATCH("/posts/:uri", postHandler.EditPost)
+		api.DELETE("/posts/:uri", postHandler.DeletePost)
+		api.DELETE("/posts/:uri/thread", postHandler.DeleteThread)
+		api.PUT("/posts/:uri/gates", postHandler.UpdateGates)
+		api.POST("/posts/:uri/like", postHandler.LikePost)
+		api.DELETE("/posts/:uri/like", postHandler.UnlikePost)
+		api.POST("/posts/:uri/repost", postHandler.RepostPost)
+		api.DELETE("/posts/:uri/repost", postHandler.UnrepostPost)
+		api.GET("/posts/scheduled", postHandler.ListScheduledPosts)
+		api.GET("/posts/scheduled/:id", postHandler.GetScheduledPost)
+		api.PATCH("/posts/scheduled/:id", postHandler.ReschedulePost)
+		api.DELETE("/posts/scheduled/:id", postHandler.CancelScheduledPost)
 		api.POST("/test/posts/create", postHandler.CreateTestPost)
+		api.GET("/accounts", postHandler.ListAccounts)
+		api.GET("/rate-limits", postHandler.RateLimits)
 	}
 
 	// Create HTTP server
@@ -99,5 +140,8 @@ func main() {
 		logger.Errorf("Server forced to shutdown: %v", err)
 	}
 
+	// Let a scheduled post that is being published finish
+	postScheduler.Stop()
+
 	logger.Info("Server exited")
 }
\ No newline at end of file
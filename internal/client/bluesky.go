@@ -2,169 +2,556 @@ package client
 
 import (
 	"fmt"
-	"math"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/think-root/bluesky-connector/internal/config"
+	"github.com/think-root/bluesky-connector/internal/langdetect"
 	"github.com/think-root/bluesky-connector/internal/logger"
 	"github.com/think-root/bluesky-connector/internal/models"
+	"github.com/think-root/bluesky-connector/internal/sessionstore"
 	"github.com/think-root/bluesky-connector/pkg/atproto"
 )
 
 const (
-	MaxPostLength = 295
+	MaxPostLength = atproto.MaxPostGraphemes
 	DelayBetweenPosts = 2 * time.Second
 )
 
 type BlueSkyClient struct {
-	config         *config.Config
+	config       *config.Config
+	account      config.AccountConfig
+	discovery    *atproto.PDSDiscovery
+	sessionStore *sessionstore.Store
+	rateLimiter  *atproto.RateLimiter
+	langDetector *langdetect.Detector
+
+	// current holds the managers of the PDS the account talks to. connect
+	// replaces them all at once, so readers take them from conn
+	current atomic.Pointer[connection]
+
+	// authErr is the error of the last failed authentication, reported by
+	// Status until a later attempt succeeds. userDID and userHandle belong to
+	// the current session
+	authMu     sync.Mutex
+	authErr    error
+	userDID    string
+	userHandle string
+
+	// loginMu keeps concurrent requests from logging in at once, and queue
+	// publishes one thread at a time
+	loginMu sync.Mutex
+	queue   publishQueue
+}
+
+// connection is the set of managers talking to one PDS. pdsURL is empty
+// until discovered when the account configures none
+type connection struct {
+	pdsURL         string
 	sessionManager *atproto.SessionManager
 	recordManager  *atproto.RecordManager
 	mediaManager   *atproto.MediaManager
-	userDID        string
-	userHandle     string
+	videoManager   *atproto.VideoManager
+	feedManager    *atproto.FeedManager
 }
 
-func NewBlueSkyClient(cfg *config.Config) *BlueSkyClient {
-	sessionManager := atproto.NewSessionManager("")
-	recordManager := atproto.NewRecordManager("", sessionManager)
-	mediaManager := atproto.NewMediaManager("", sessionManager)
+// NewBlueSkyClient creates a client publishing as account, talking to the
+// account's PDS. Without a configured PDS it is discovered on the first
+// authentication
+func NewBlueSkyClient(cfg *config.Config, account config.AccountConfig) *BlueSkyClient {
+	// Detection stays nil when disabled, leaving langs unset
+	var langDetector *langdetect.Detector
+	if cfg.Bluesky.LangDetection {
+		langDetector = langdetect.New(cfg.Bluesky.LangCandidates)
+	}
+
+	c := &BlueSkyClient{
+		config:       cfg,
+		account:      account,
+		discovery:    atproto.NewPDSDiscovery(cfg.Bluesky.PLCDirectoryURL),
+		sessionStore: newSessionStore(cfg.Session, account),
+		rateLimiter:  atproto.NewRateLimiter(cfg.Bluesky.RateLimitMaxWait),
+		langDetector: langDetector,
+	}
+	c.connect(account.PDSURL)
+	return c
+}
 
-	return &BlueSkyClient{
-		config:         cfg,
+// connect points a new set of managers at pdsURL and makes them current
+func (c *BlueSkyClient) connect(pdsURL string) *connection {
+	sessionManager := atproto.NewSessionManager(pdsURL)
+	sessionManager.UseRateLimiter(c.rateLimiter)
+	sessionManager.SetCredentials(c.account.Handle, c.account.AppPassword)
+	sessionManager.OnRefresh(func(session *models.CreateSessionResponse) {
+		c.saveSession(pdsURL, session)
+	})
+	identityResolver := atproto.NewIdentityResolver(pdsURL, sessionManager)
+	mediaManager := atproto.NewMediaManager(pdsURL, sessionManager)
+
+	conn := &connection{
+		pdsURL:         pdsURL,
 		sessionManager: sessionManager,
-		recordManager:  recordManager,
+		recordManager:  atproto.NewRecordManager(pdsURL, sessionManager, identityResolver),
 		mediaManager:   mediaManager,
+		videoManager:   atproto.NewVideoManager(c.config.Bluesky.VideoServiceURL, c.config.Bluesky.VideoServiceAudience, sessionManager, mediaManager),
+		feedManager:    atproto.NewFeedManager(pdsURL, sessionManager, identityResolver),
+	}
+
+	// The replaced session must stop refreshing itself in the background
+	if previous := c.current.Swap(conn); previous != nil {
+		previous.sessionManager.ClearSession()
 	}
+	return conn
+}
+
+// conn returns the managers of the PDS the account currently talks to
+func (c *BlueSkyClient) conn() *connection {
+	return c.current.Load()
+}
+
+// identity returns the DID and handle of the authenticated account
+func (c *BlueSkyClient) identity() (did, handle string) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	return c.userDID, c.userHandle
+}
+
+func (c *BlueSkyClient) setIdentity(did, handle string) {
+	c.authMu.Lock()
+	c.userDID = did
+	c.userHandle = handle
+	c.authMu.Unlock()
 }
 
 func (c *BlueSkyClient) Authenticate() error {
-	logger.Info("Authenticating with Bluesky...")
-	
-	session, err := c.sessionManager.CreateSession(c.config.Bluesky.Handle, c.config.Bluesky.AppPassword)
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+	return c.authenticate()
+}
+
+// authenticate logs in, resuming the saved session when there is one.
+// Callers must hold c.loginMu
+func (c *BlueSkyClient) authenticate() error {
+	logger.Infof("Authenticating account %s with Bluesky...", c.account.Name)
+
+	if c.resumeSession() {
+		c.authMu.Lock()
+		c.authErr = nil
+		c.authMu.Unlock()
+		return nil
+	}
+
+	conn, err := c.discoverPDS()
+	if err != nil {
+		c.authMu.Lock()
+		c.authErr = err
+		c.authMu.Unlock()
+		return err
+	}
+
+	session, err := conn.sessionManager.CreateSession(c.account.Handle, c.account.AppPassword)
+
+	c.authMu.Lock()
+	c.authErr = err
+	c.authMu.Unlock()
+
 	if err != nil {
 		return fmt.Errorf("failed to create session: %w", err)
 	}
 
-	c.userDID = session.DID
-	c.userHandle = session.Handle
+	c.setIdentity(session.DID, session.Handle)
+	c.saveSession(conn.pdsURL, session)
 
-	logger.Infof("Successfully authenticated as %s (%s)", c.userHandle, c.userDID)
+	logger.Infof("Successfully authenticated as %s (%s)", session.Handle, session.DID)
 	return nil
 }
 
-func (c *BlueSkyClient) splitTextIntoParts(text string) []string {
-	if len(text) <= MaxPostLength {
-		return []string{text}
+// discoverPDS looks up the account's PDS from its DID document unless one is
+// configured or was discovered before, returning the managers talking to it
+func (c *BlueSkyClient) discoverPDS() (*connection, error) {
+	if conn := c.conn(); conn.pdsURL != "" {
+		return conn, nil
+	}
+
+	pdsURL, err := c.discovery.DiscoverPDS(c.account.Handle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover PDS: %w", err)
 	}
 
-	totalParts := int(math.Ceil(float64(len(text)) / float64(MaxPostLength)))
-	targetLength := int(math.Ceil(float64(len(text)) / float64(totalParts)))
-	
-	var parts []string
-	remaining := text
+	logger.Infof("Discovered PDS %s for %s", pdsURL, c.account.Handle)
+	return c.connect(pdsURL), nil
+}
 
-	for len(remaining) > 0 {
-		if len(remaining) <= MaxPostLength {
-			parts = append(parts, remaining)
-			break
-		}
+// AccountName returns the name of the configured account the client posts as
+func (c *BlueSkyClient) AccountName() string {
+	return c.account.Name
+}
+
+// Status reports the account the client posts as and whether it is
+// authenticated
+func (c *BlueSkyClient) Status() models.AccountStatus {
+	conn := c.conn()
+	status := models.AccountStatus{
+		Name:          c.account.Name,
+		Handle:        c.account.Handle,
+		PDS:           conn.pdsURL,
+		Authenticated: conn.sessionManager.IsAuthenticated(),
+		QueueDepth:    c.queue.depth(),
+	}
+
+	c.authMu.Lock()
+	if status.Authenticated {
+		status.DID = c.userDID
+	}
+	if c.authErr != nil {
+		status.Error = c.authErr.Error()
+	}
+	c.authMu.Unlock()
+
+	return status
+}
+
+// RateLimits reports the rate limit budgets the account's hosts last reported
+func (c *BlueSkyClient) RateLimits() models.AccountRateLimits {
+	return models.AccountRateLimits{
+		Account: c.account.Name,
+		Limits:  c.rateLimiter.Budgets(),
+	}
+}
+
+// hashtagsFor returns the hashtags to add to a post: the request's own, or the
+// configured ones when it has none, minus any tag the text already contains
+func (c *BlueSkyClient) hashtagsFor(content *models.PostContent) []string {
+	tags := content.Hashtags
+	if tags == nil {
+		tags = c.config.Bluesky.Hashtags
+	}
+
+	present := make(map[string]bool)
+	for _, facet := range atproto.DetectHashtags(content.Text) {
+		present[strings.ToLower(facet.Features[0].Tag)] = true
+	}
 
-		// Find a good split point near the target length
-		start := max(targetLength-20, 0)
-		end := min(targetLength+20, len(remaining))
-		
-		splitIndex := strings.LastIndex(remaining[start:end], " ")
-		if splitIndex == -1 {
-			// If no space found, look for any space before MaxPostLength
-			splitIndex = strings.LastIndex(remaining[:MaxPostLength], " ")
-			if splitIndex == -1 {
-				// Force split at MaxPostLength
-				splitIndex = MaxPostLength
+	var result []string
+	for _, tag := range tags {
+		for _, field := range strings.Fields(strings.ReplaceAll(tag, ",", " ")) {
+			name := strings.TrimLeft(field, "#")
+			if name == "" || present[strings.ToLower(name)] {
+				continue
 			}
-		} else {
-			splitIndex += start
+			present[strings.ToLower(name)] = true
+			result = append(result, "#"+name)
 		}
+	}
+
+	return result
+}
+
+// langsFor returns the language tags of a post: the requested ones, or the
+// language detected from text when none were given
+func (c *BlueSkyClient) langsFor(langs []string, text string) []string {
+	if len(langs) > 0 || c.langDetector == nil {
+		return langs
+	}
 
-		parts = append(parts, strings.TrimSpace(remaining[:splitIndex]))
-		remaining = strings.TrimSpace(remaining[splitIndex:])
+	detected := c.langDetector.Detect(text)
+	if detected == nil {
+		logger.Info("Could not detect the language of the post, leaving langs unset")
+	} else {
+		logger.Infof("Detected post language: %s", strings.Join(detected, ", "))
 	}
+	return detected
+}
+
+func (c *BlueSkyClient) hashtagPlacementFor(content *models.PostContent) string {
+	if content.HashtagPlacement != "" {
+		return content.HashtagPlacement
+	}
+	return c.config.Bluesky.HashtagPlacement
+}
+
+// composeParts splits the text into thread parts and adds the hashtags to the
+// first or last part. When they go first, the first part is split short
+// enough to leave room for them
+func (c *BlueSkyClient) composeParts(text string, hashtags []string, placement string) []string {
+	if len(hashtags) == 0 {
+		return c.splitTextIntoParts(text)
+	}
+
+	suffix := "\n\n" + strings.Join(hashtags, " ")
 
+	if placement != config.HashtagPlacementFirst || atproto.GraphemeLength(text+suffix) <= MaxPostLength {
+		return c.splitTextIntoParts(text + suffix)
+	}
+
+	parts := splitThread(text, atproto.GraphemeLength(suffix))
+	parts[0] += suffix
 	return parts
 }
 
-func (c *BlueSkyClient) PostWithMedia(text, url string, imageData []byte) (*models.CreatePostResponse, error) {
-	if !c.sessionManager.IsAuthenticated() {
-		if err := c.Authenticate(); err != nil {
-			return nil, fmt.Errorf("authentication failed: %w", err)
+// threadPost is a single post of a thread waiting to be published. At most
+// one of images, video and linkURL is set; quoted may accompany any of them
+type threadPost struct {
+	text    string
+	images  []models.ImageUpload
+	video   *models.VideoUpload
+	linkURL string
+	quoted  *models.PostRef
+	langs   []string
+	labels  []string
+}
+
+// threadOptions are the settings publishThread applies to a whole thread
+type threadOptions struct {
+	// replyTo makes the first post a reply to an existing post instead of
+	// the root of a new thread
+	replyTo       *models.Reply
+	replyGate     *models.ReplyGate
+	disableQuotes bool
+}
+
+// newRecord builds the record of a post with its langs and, when it carries
+// media, its self-labels
+func (c *BlueSkyClient) newRecord(post *threadPost, reply *models.Reply, embed *models.Embed) models.PostRecord {
+	record := c.conn().recordManager.NewPostRecord(post.text, reply, embed)
+	record.Langs = post.langs
+	if len(post.images) > 0 || post.video != nil {
+		record.Labels = atproto.NewSelfLabels(post.labels)
+	}
+	return record
+}
+
+func (c *BlueSkyClient) ensureAuthenticated() error {
+	if c.conn().sessionManager.IsAuthenticated() {
+		return nil
+	}
+
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+
+	if !c.conn().sessionManager.IsAuthenticated() {
+		if err := c.authenticate(); err != nil {
+			return fmt.Errorf("authentication failed: %w", err)
 		}
 	}
+	return nil
+}
 
-	textWithHashtags := text + "\n\n#GitHub #OpenSource"
+// resolveReply resolves the post to reply to, returning nil when there is none
+func (c *BlueSkyClient) resolveReply(ref string) (*models.Reply, error) {
+	if ref == "" {
+		return nil, nil
+	}
 
-	var posts []models.CreateRecordResponse
-	textParts := c.splitTextIntoParts(textWithHashtags)
-	totalParts := len(textParts)
+	reply, err := c.conn().feedManager.ResolveReply(ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve post to reply to: %w", err)
+	}
+	logger.Infof("Replying to post %s in thread %s", reply.Parent.URI, reply.Root.URI)
+	return reply, nil
+}
 
-	logger.Infof("Posting content in %d parts", totalParts)
+// resolveQuote resolves a quoted post reference, returning nil when there is none
+func (c *BlueSkyClient) resolveQuote(quote string) (*models.PostRef, error) {
+	if quote == "" {
+		return nil, nil
+	}
+
+	quoted, err := c.conn().feedManager.ResolvePostRef(quote)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve quoted post: %w", err)
+	}
+	logger.Infof("Quoting post %s", quoted.URI)
+	return quoted, nil
+}
+
+// PostWithMedia publishes text as a single post, or as a numbered thread when
+// it is too long, with the media and quote on the first post and the URL as a
+// link card in a final reply. With content.ReplyTo the posts continue the
+// thread of that post
+func (c *BlueSkyClient) PostWithMedia(content *models.PostContent) (*models.CreatePostResponse, error) {
+	if err := c.ensureAuthenticated(); err != nil {
+		return nil, err
+	}
+
+	posts, err := c.planPosts(content)
+	if err != nil {
+		return nil, err
+	}
+
+	replyTo, err := c.resolveReply(content.ReplyTo)
+	if err != nil {
+		return nil, err
+	}
 
-	var previousPost *models.CreateRecordResponse
-	var rootPost *models.CreateRecordResponse
+	return c.publishThread(posts, threadOptions{
+		replyTo:       replyTo,
+		replyGate:     content.ReplyGate,
+		disableQuotes: content.DisableQuotes,
+	})
+}
+
+// planPosts lays out the posts PostWithMedia publishes for content
+func (c *BlueSkyClient) planPosts(content *models.PostContent) ([]threadPost, error) {
+	// Resolve the quoted post up front so a bad reference fails before anything is published
+	quoted, err := c.resolveQuote(content.Quote)
+	if err != nil {
+		return nil, err
+	}
+
+	// Detect on the whole text, before hashtags and thread numbering are added
+	langs := c.langsFor(content.Langs, content.Text)
 
+	hashtags := c.hashtagsFor(content)
+	textParts := c.composeParts(content.Text, hashtags, c.hashtagPlacementFor(content))
+
+	posts := make([]threadPost, 0, len(textParts)+1)
 	for i, part := range textParts {
-		var postText string
-		if totalParts > 1 {
-			postText = fmt.Sprintf("🧵 %d/%d %s", i, totalParts-1, part)
-		} else {
-			postText = part
+		post := threadPost{text: part, langs: langs}
+		if len(textParts) > 1 {
+			post.text = threadPrefix(i, len(textParts)) + part
 		}
 
-		var reply *models.Reply
-		var postEmbed *models.Embed
-
-		// Add image to first post only
-		if i == 0 && imageData != nil {
-			logger.Info("Uploading image for first post")
-			mimeType := atproto.DetectMimeType(imageData)
-			var err error
-			postEmbed, err = c.mediaManager.CreateImageEmbed(imageData, mimeType, "Image")
-			if err != nil {
-				logger.Errorf("Failed to create image embed: %v", err)
-				return nil, fmt.Errorf("failed to create image embed: %w", err)
-			}
-			logger.Info("Image uploaded successfully")
+		// Media and quote go on the first post only
+		if i == 0 {
+			post.images = content.Images
+			post.video = content.Video
+			post.quoted = quoted
+			post.labels = content.Labels
+		}
+
+		posts = append(posts, post)
+	}
+
+	// Add URL as final reply if provided
+	if content.URL != "" {
+		posts = append(posts, threadPost{text: content.URL, linkURL: content.URL, langs: langs})
+	}
+
+	return posts, nil
+}
+
+// PostThread publishes the parts exactly as given, one post per part, all
+// tagged with langs or the language detected from their text. Every part is
+// checked against the length limit and every quote resolved before the first
+// post is published. A non-empty replyTo makes the first part a reply to that
+// post
+func (c *BlueSkyClient) PostThread(parts []models.ThreadPart, langs []string, replyTo string) (*models.CreatePostResponse, error) {
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("thread has no parts")
+	}
+
+	for i, part := range parts {
+		if length := atproto.GraphemeLength(part.Text); length > MaxPostLength {
+			return nil, fmt.Errorf("part %d is %d graphemes long (max %d)", i+1, length, MaxPostLength)
+		}
+	}
+
+	if err := c.ensureAuthenticated(); err != nil {
+		return nil, err
+	}
+
+	reply, err := c.resolveReply(replyTo)
+	if err != nil {
+		return nil, err
+	}
+
+	texts := make([]string, len(parts))
+	for i, part := range parts {
+		texts[i] = part.Text
+	}
+	langs = c.langsFor(langs, strings.Join(texts, "\n\n"))
+
+	posts := make([]threadPost, 0, len(parts))
+	for i, part := range parts {
+		quoted, err := c.resolveQuote(part.Quote)
+		if err != nil {
+			return nil, fmt.Errorf("part %d: %w", i+1, err)
+		}
+
+		posts = append(posts, threadPost{
+			text:    part.Text,
+			images:  part.Images,
+			video:   part.Video,
+			linkURL: part.URL,
+			quoted:  quoted,
+			langs:   langs,
+			labels:  part.Labels,
+		})
+	}
+
+	return c.publishThread(posts, threadOptions{replyTo: reply})
+}
+
+// publishThread publishes the posts in order, each replying to the previous
+// one and the first to opts.replyTo when set. The reply gate is written as
+// soon as the root post exists, and with disableQuotes every post gets a
+// postgate. Failing to write a gate does not stop the thread and is reported
+// as a warning. Threads of the account are published one at a time, in the
+// order they arrive. When a post fails, the posts published before it are
+// returned along with the error
+func (c *BlueSkyClient) publishThread(posts []threadPost, opts threadOptions) (*models.CreatePostResponse, error) {
+	queue := c.queue.acquire()
+	defer c.queue.release()
+	if queue.Depth > 0 {
+		logger.Infof("Waited %dms behind %d thread(s) of account %s", queue.WaitMS, queue.Depth, c.account.Name)
+	}
+
+	conn := c.conn()
+	did, _ := c.identity()
+
+	totalParts := len(posts)
+	logger.Infof("Posting content in %d parts", totalParts)
+
+	var results []models.CreateRecordResponse
+	var warnings []string
+	var previousPost *models.PostRef
+	var rootPost *models.PostRef
+	if opts.replyTo != nil {
+		rootPost = opts.replyTo.Root
+		previousPost = opts.replyTo.Parent
+	}
+
+	for i, post := range posts {
+		postEmbed, err := c.createEmbed(&post)
+		if err != nil {
+			return partial(results, warnings, queue), fmt.Errorf("post %d: %w", i+1, err)
 		}
 
 		// Set up reply structure for thread
-		if i > 0 && previousPost != nil {
-			if rootPost == nil {
-				rootPost = previousPost
-			}
+		var reply *models.Reply
+		if previousPost != nil {
 			reply = &models.Reply{
-				Root: &models.PostRef{
-					URI: rootPost.URI,
-					CID: rootPost.CID,
-				},
-				Parent: &models.PostRef{
-					URI: previousPost.URI,
-					CID: previousPost.CID,
-				},
+				Root:   rootPost,
+				Parent: previousPost,
 			}
 		}
 
-		logger.Infof("Creating post %d/%d: %s...", i+1, totalParts, postText[:min(50, len(postText))])
+		logger.Infof("Creating post %d/%d: %s...", i+1, totalParts, post.text[:min(50, len(post.text))])
 
-		post, err := c.recordManager.CreatePost(c.userDID, postText, reply, postEmbed)
+		created, err := conn.recordManager.CreatePostRecord(did, c.newRecord(&post, reply, postEmbed))
 		if err != nil {
-			return nil, fmt.Errorf("failed to create post %d: %w", i+1, err)
+			return partial(results, warnings, queue), fmt.Errorf("failed to create post %d: %w", i+1, err)
 		}
 
-		posts = append(posts, *post)
-		previousPost = post
+		results = append(results, *created)
+		previousPost = &models.PostRef{URI: created.URI, CID: created.CID}
 
 		if rootPost == nil {
-			rootPost = post
+			rootPost = previousPost
+			if opts.replyGate != nil {
+				warnings = append(warnings, c.applyGate(created, "threadgate", func(uri *atproto.ATURI) error {
+					return conn.recordManager.SetThreadgate(uri, opts.replyGate)
+				})...)
+			}
+		}
+
+		if opts.disableQuotes {
+			warnings = append(warnings, c.applyGate(created, "postgate", func(uri *atproto.ATURI) error {
+				return conn.recordManager.SetPostgate(uri, true)
+			})...)
 		}
 
 		// Wait between posts to avoid rate limiting
@@ -174,42 +561,75 @@ func (c *BlueSkyClient) PostWithMedia(text, url string, imageData []byte) (*mode
 		}
 	}
 
+	logger.Infof("Successfully posted %d posts", len(results))
+	return &models.CreatePostResponse{Posts: results, Warnings: warnings, Queue: queue}, nil
+}
 
-	// Add URL as final reply if provided
-	if url != "" && previousPost != nil {
-		logger.Infof("Adding URL as final reply: %s", url)
-		
-		reply := &models.Reply{
-			Root: &models.PostRef{
-				URI: rootPost.URI,
-				CID: rootPost.CID,
-			},
-			Parent: &models.PostRef{
-				URI: previousPost.URI,
-				CID: previousPost.CID,
-			},
+// partial returns the posts a failed thread published before failing, or nil
+// when it failed before publishing any
+func partial(results []models.CreateRecordResponse, warnings []string, queue *models.QueueInfo) *models.CreatePostResponse {
+	if len(results) == 0 {
+		return nil
+	}
+	return &models.CreatePostResponse{Posts: results, Warnings: warnings, Queue: queue}
+}
+
+// applyGate writes a gate of a published post, returning a warning when it
+// could not be written
+func (c *BlueSkyClient) applyGate(post *models.CreateRecordResponse, kind string, apply func(*atproto.ATURI) error) []string {
+	uri, err := atproto.ParseATURI(post.URI)
+	if err == nil {
+		err = apply(uri)
+	}
+	if err != nil {
+		logger.Errorf("Failed to write %s for %s: %v", kind, post.URI, err)
+		return []string{fmt.Sprintf("failed to write %s for %s: %v", kind, post.URI, err)}
+	}
+	return nil
+}
+
+// createEmbed uploads the media of a post and builds its embed, returning nil
+// for posts without media or quote
+func (c *BlueSkyClient) createEmbed(post *threadPost) (*models.Embed, error) {
+	var postEmbed *models.Embed
+	var err error
+
+	switch {
+	case len(post.images) > 0:
+		logger.Infof("Uploading %d image(s)", len(post.images))
+		postEmbed, err = c.conn().mediaManager.CreateImagesEmbed(post.images)
+		if err != nil {
+			logger.Errorf("Failed to create image embed: %v", err)
+			return nil, fmt.Errorf("failed to create image embed: %w", err)
 		}
+		logger.Info("Images uploaded successfully")
 
-		time.Sleep(DelayBetweenPosts)
+	case post.video != nil:
+		logger.Info("Uploading video")
+		did, _ := c.identity()
+		postEmbed, err = c.conn().videoManager.CreateVideoEmbed(did, *post.video)
+		if err != nil {
+			logger.Errorf("Failed to create video embed: %v", err)
+			return nil, fmt.Errorf("failed to create video embed: %w", err)
+		}
+		logger.Info("Video uploaded successfully")
 
+	case post.linkURL != "":
 		// Create external embed with OG metadata
-		urlEmbed, err := c.mediaManager.CreateExternalEmbed(url)
+		logger.Infof("Adding link card for %s", post.linkURL)
+		postEmbed, err = c.conn().mediaManager.CreateExternalEmbed(post.linkURL)
 		if err != nil {
 			logger.Errorf("Failed to create external embed: %v", err)
 			return nil, fmt.Errorf("failed to create external embed: %w", err)
 		}
+	}
 
-		urlPost, err := c.recordManager.CreatePost(c.userDID, url, reply, urlEmbed)
-		if err != nil {
-			logger.Errorf("Failed to add URL reply: %v", err)
-			return nil, fmt.Errorf("failed to add URL reply: %w", err)
-		}
-
-		posts = append(posts, *urlPost)
+	// Quote the post alongside any media
+	if post.quoted != nil {
+		postEmbed = atproto.CreateQuoteEmbed(post.quoted, postEmbed)
 	}
 
-	logger.Infof("Successfully posted %d posts", len(posts))
-	return &models.CreatePostResponse{Posts: posts}, nil
+	return postEmbed, nil
 }
 
 func min(a, b int) int {
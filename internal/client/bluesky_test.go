@@ -1,9 +1,15 @@
 package client
 
 import (
+	"strings"
+	"sync"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/think-root/bluesky-connector/internal/config"
+	"github.com/think-root/bluesky-connector/internal/models"
+	"github.com/think-root/bluesky-connector/pkg/atproto"
 )
 
 func TestSplitTextIntoParts(t *testing.T) {
@@ -62,4 +68,274 @@ func TestMinMax(t *testing.T) {
 	assert.Equal(t, 5, min(10, 5))
 	assert.Equal(t, 10, max(5, 10))
 	assert.Equal(t, 10, max(10, 5))
-}
\ No newline at end of file
+}
+func TestHashtagsFor(t *testing.T) {
+	client := &BlueSkyClient{
+		config: &config.Config{
+			Bluesky: config.BlueSkyConfig{
+				Hashtags: []string{"#GitHub", "#OpenSource"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		content  models.PostContent
+		expected []string
+	}{
+		{
+			name:     "Configured hashtags",
+			content:  models.PostContent{Text: "New release"},
+			expected: []string{"#GitHub", "#OpenSource"},
+		},
+		{
+			name:     "Skips tags already in text",
+			content:  models.PostContent{Text: "New release on #github"},
+			expected: []string{"#OpenSource"},
+		},
+		{
+			name:     "Request override without # and with duplicates",
+			content:  models.PostContent{Text: "New release", Hashtags: []string{"Go, golang", "#Go"}},
+			expected: []string{"#Go", "#golang"},
+		},
+		{
+			name:     "Disabled",
+			content:  models.PostContent{Text: "New release", Hashtags: []string{}},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, client.hashtagsFor(&tt.content))
+		})
+	}
+}
+
+func TestComposeParts(t *testing.T) {
+	client := &BlueSkyClient{}
+	hashtags := []string{"#GitHub", "#OpenSource"}
+	longText := strings.Repeat("word ", 100)
+
+	t.Run("Short text gets hashtags in the same post", func(t *testing.T) {
+		parts := client.composeParts("Hello", hashtags, config.HashtagPlacementFirst)
+		assert.Equal(t, []string{"Hello\n\n#GitHub #OpenSource"}, parts)
+	})
+
+	t.Run("Last placement", func(t *testing.T) {
+		parts := client.composeParts(longText, hashtags, config.HashtagPlacementLast)
+		assert.Greater(t, len(parts), 1)
+		assert.True(t, strings.HasSuffix(parts[len(parts)-1], "#GitHub #OpenSource"))
+		assert.NotContains(t, parts[0], "#GitHub")
+	})
+
+	t.Run("First placement", func(t *testing.T) {
+		parts := client.composeParts(longText, hashtags, config.HashtagPlacementFirst)
+		assert.Greater(t, len(parts), 1)
+		assert.True(t, strings.HasSuffix(parts[0], "#GitHub #OpenSource"))
+		assert.LessOrEqual(t, len(parts[0]), MaxPostLength)
+		assert.NotContains(t, parts[len(parts)-1], "#GitHub")
+	})
+
+	t.Run("No hashtags", func(t *testing.T) {
+		assert.Equal(t, []string{"Hello"}, client.composeParts("Hello", nil, config.HashtagPlacementLast))
+	})
+}
+
+func TestSplitTextIntoParts_Graphemes(t *testing.T) {
+	client := &BlueSkyClient{}
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{
+			name:  "Cyrillic text",
+			input: strings.Repeat("Привіт, це довгий український текст для перевірки. ", 12),
+		},
+		{
+			name:  "Emoji with modifiers and ZWJ sequences",
+			input: strings.Repeat("👩‍💻🏳️‍🌈👍🏽 ", 120),
+		},
+		{
+			name:  "Single long word",
+			input: strings.Repeat("ї", 700),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := client.splitTextIntoParts(tt.input)
+			assert.Greater(t, len(parts), 1)
+
+			for i, part := range parts {
+				postText := threadPrefix(i, len(parts)) + part
+				assert.LessOrEqual(t, atproto.GraphemeLength(postText), MaxPostLength, "Part %d exceeds max length", i)
+				assert.True(t, utf8.ValidString(part), "Part %d is not valid UTF-8", i)
+			}
+
+			assert.Equal(t,
+				strings.Join(strings.Fields(tt.input), ""),
+				strings.Join(strings.Fields(strings.Join(parts, "")), ""),
+				"Splitting must not lose content")
+		})
+	}
+}
+
+func TestSplitTextIntoParts_Boundaries(t *testing.T) {
+	client := &BlueSkyClient{}
+
+	t.Run("Prefers paragraph breaks", func(t *testing.T) {
+		first := strings.Repeat("First paragraph sentence. ", 8)
+		second := strings.Repeat("Second paragraph words ", 12)
+		parts := client.splitTextIntoParts(strings.TrimSpace(first) + "\n\n" + second)
+
+		assert.Equal(t, strings.TrimSpace(first), parts[0])
+	})
+
+	t.Run("Prefers sentence ends over words", func(t *testing.T) {
+		text := strings.Repeat("This sentence keeps going. ", 20)
+		parts := client.splitTextIntoParts(text)
+
+		for _, part := range parts[:len(parts)-1] {
+			assert.True(t, strings.HasSuffix(part, "."), "Part should end a sentence: %q", part)
+		}
+	})
+
+	t.Run("Never breaks URLs, hashtags or mentions", func(t *testing.T) {
+		url := "https://github.com/think-root/bluesky-connector/blob/main/internal/client/bluesky.go"
+		text := strings.Repeat("word ", 50) + url + " " + strings.Repeat("#OpenSource @alice.bsky.social ", 10)
+		parts := client.splitTextIntoParts(text)
+
+		assert.Greater(t, len(parts), 1)
+		for _, part := range parts {
+			for _, word := range strings.Fields(part) {
+				if strings.HasPrefix(word, "http") {
+					assert.Equal(t, url, word)
+				}
+				if strings.HasPrefix(word, "#") {
+					assert.Equal(t, "#OpenSource", word)
+				}
+				if strings.HasPrefix(word, "@") {
+					assert.Equal(t, "@alice.bsky.social", word)
+				}
+			}
+		}
+	})
+
+	t.Run("Short text is left alone", func(t *testing.T) {
+		text := strings.Repeat("ї", MaxPostLength)
+		assert.Equal(t, []string{text}, client.splitTextIntoParts(text))
+	})
+}
+
+func TestOwnPostsDepthFirst(t *testing.T) {
+	client := &BlueSkyClient{userDID: "did:plc:me"}
+
+	post := func(uri, did string, replies ...models.ThreadViewPost) models.ThreadViewPost {
+		return models.ThreadViewPost{
+			Type:    "app.bsky.feed.defs#threadViewPost",
+			Post:    &models.PostView{URI: uri, Author: models.ProfileViewBasic{DID: did}},
+			Replies: replies,
+		}
+	}
+
+	thread := post("root", "did:plc:me",
+		post("part2", "did:plc:me",
+			post("part3", "did:plc:me"),
+			post("other-reply", "did:plc:other",
+				post("my-answer", "did:plc:me"),
+			),
+		),
+		models.ThreadViewPost{Type: "app.bsky.feed.defs#notFoundPost"},
+	)
+
+	uris := client.ownPostsDepthFirst(&thread, nil)
+
+	assert.Equal(t, []string{"part3", "my-answer", "part2", "root"}, uris)
+}
+
+func TestDeleteThread_RejectsOtherAccountsRoot(t *testing.T) {
+	c := NewBlueSkyClient(&config.Config{}, config.AccountConfig{Name: "default", Handle: "alice.bsky.social"})
+	c.conn().sessionManager.ResumeSession("access", "refresh")
+	c.setIdentity("did:plc:me", "alice.bsky.social")
+
+	// Rejected before the thread is fetched, so nothing of it is deleted
+	_, err := c.DeleteThread("at://did:plc:other/app.bsky.feed.post/3k2a")
+	assert.ErrorIs(t, err, ErrNotOwnPost)
+}
+
+func TestStatus_WhileReconnecting(t *testing.T) {
+	account := config.AccountConfig{Name: "default", Handle: "alice.bsky.social"}
+	c := NewBlueSkyClient(&config.Config{}, account)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			c.connect("https://pds.example.com")
+			c.setIdentity("did:plc:alice", account.Handle)
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		status := c.Status()
+		assert.False(t, status.Authenticated)
+	}
+	wg.Wait()
+
+	assert.Equal(t, "https://pds.example.com", c.Status().PDS)
+}
+
+func TestPreviewFacets(t *testing.T) {
+	text := "Привіт #golang see https://go.dev"
+	facets := append(atproto.DetectHashtags(text), atproto.DetectLinks(text)...)
+
+	preview := previewFacets(text, facets)
+
+	assert.Equal(t, []models.PreviewFacet{
+		{Type: "tag", Text: "#golang", ByteStart: 13, ByteEnd: 20, Value: "golang"},
+		{Type: "link", Text: "https://go.dev", ByteStart: 25, ByteEnd: 39, Value: "https://go.dev"},
+	}, preview)
+}
+
+func TestPostStatus(t *testing.T) {
+	post := &models.PostView{
+		URI:       "at://did:plc:me/app.bsky.feed.post/3k2a",
+		CID:       "cid",
+		Author:    models.ProfileViewBasic{DID: "did:plc:me", Handle: "me.bsky.social"},
+		Record:    []byte(`{"$type":"app.bsky.feed.post","text":"Release notes","createdAt":"2025-01-02T03:04:05Z"}`),
+		IndexedAt: "2025-01-02T03:04:06Z",
+		Viewer:    &models.PostViewerState{Like: "at://did:plc:me/app.bsky.feed.like/3k2b"},
+
+		ReplyCount:  2,
+		RepostCount: 3,
+		LikeCount:   5,
+		QuoteCount:  1,
+		Labels: []models.Label{
+			{Src: "did:plc:me", Val: "graphic-media"},
+			{Src: "did:plc:labeler", Val: "graphic-media"},
+			{Src: "did:plc:labeler", Val: "spam", Neg: true},
+		},
+	}
+
+	status, err := postStatus(post)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.PostStatusResponse{
+		URI:         post.URI,
+		CID:         "cid",
+		Visible:     true,
+		Status:      models.PostStatusVisible,
+		Author:      "me.bsky.social",
+		Text:        "Release notes",
+		CreatedAt:   "2025-01-02T03:04:05Z",
+		IndexedAt:   "2025-01-02T03:04:06Z",
+		ReplyCount:  2,
+		RepostCount: 3,
+		LikeCount:   5,
+		QuoteCount:  1,
+		Labels:      []string{"graphic-media"},
+		Like:        "at://did:plc:me/app.bsky.feed.like/3k2b",
+	}, status)
+}
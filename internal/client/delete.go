@@ -0,0 +1,107 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/think-root/bluesky-connector/internal/logger"
+	"github.com/think-root/bluesky-connector/internal/models"
+	"github.com/think-root/bluesky-connector/pkg/atproto"
+)
+
+// ErrNotOwnPost is returned when asked to change a post of another account
+var ErrNotOwnPost = errors.New("post was not published by this account")
+
+// ownPostURI resolves a post AT-URI or bsky.app URL and checks that it
+// belongs to the authenticated account
+func (c *BlueSkyClient) ownPostURI(ref string) (*atproto.ATURI, error) {
+	uri, err := c.conn().feedManager.ResolvePostURI(ref)
+	if err != nil {
+		return nil, err
+	}
+	if did, _ := c.identity(); uri.Repo != did {
+		return nil, fmt.Errorf("%w: %s", ErrNotOwnPost, uri)
+	}
+	return uri, nil
+}
+
+// DeletePost deletes a single post of the authenticated account
+func (c *BlueSkyClient) DeletePost(ref string) (*models.DeletePostResponse, error) {
+	if err := c.ensureAuthenticated(); err != nil {
+		return nil, err
+	}
+
+	uri, err := c.ownPostURI(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.conn().recordManager.DeleteRecord(uri); err != nil {
+		return nil, fmt.Errorf("failed to delete post %s: %w", uri, err)
+	}
+
+	logger.Infof("Deleted post %s", uri)
+	return &models.DeletePostResponse{Deleted: []string{uri.String()}}, nil
+}
+
+// DeleteThread deletes the root post, which must belong to the authenticated
+// account, and every reply below it that the account published, leaving
+// replies by other accounts alone.
+// Replies are deleted before the posts they answer. Failures do not stop the
+// remaining deletions and are reported in the response
+func (c *BlueSkyClient) DeleteThread(rootRef string) (*models.DeletePostResponse, error) {
+	if err := c.ensureAuthenticated(); err != nil {
+		return nil, err
+	}
+
+	root, err := c.ownPostURI(rootRef)
+	if err != nil {
+		return nil, err
+	}
+
+	thread, err := c.conn().feedManager.GetPostThread(root.String(), atproto.MaxThreadDepth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch thread %s: %w", root, err)
+	}
+	if thread.Post == nil {
+		return nil, fmt.Errorf("thread root %s is not available (%s)", root, thread.Type)
+	}
+
+	uris := c.ownPostsDepthFirst(thread, nil)
+	logger.Infof("Deleting %d post(s) of thread %s", len(uris), root)
+
+	result := &models.DeletePostResponse{Deleted: []string{}}
+	for _, uri := range uris {
+		parsed, err := atproto.ParseATURI(uri)
+		if err == nil {
+			err = c.conn().recordManager.DeleteRecord(parsed)
+		}
+		if err != nil {
+			logger.Errorf("Failed to delete post %s: %v", uri, err)
+			result.Failed = append(result.Failed, models.DeleteFailure{URI: uri, Error: err.Error()})
+			continue
+		}
+		result.Deleted = append(result.Deleted, uri)
+	}
+
+	logger.Infof("Deleted %d post(s) of thread %s, %d failed", len(result.Deleted), root, len(result.Failed))
+	return result, nil
+}
+
+// ownPostsDepthFirst appends the URIs of the account's posts in the thread,
+// every reply before its parent
+func (c *BlueSkyClient) ownPostsDepthFirst(node *models.ThreadViewPost, uris []string) []string {
+	if node.Post == nil {
+		return uris
+	}
+
+	for i := range node.Replies {
+		uris = c.ownPostsDepthFirst(&node.Replies[i], uris)
+	}
+
+	if did, _ := c.identity(); node.Post.Author.DID == did {
+		uris = append(uris, node.Post.URI)
+	}
+
+	return uris
+}
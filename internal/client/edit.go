@@ -0,0 +1,52 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/think-root/bluesky-connector/internal/logger"
+	"github.com/think-root/bluesky-connector/internal/models"
+	"github.com/think-root/bluesky-connector/pkg/atproto"
+)
+
+// EditPost replaces the text of a post of the authenticated account. The
+// post's embed is replaced when the edit carries media, a URL or a quote,
+// removed when removeEmbed is set and kept otherwise
+func (c *BlueSkyClient) EditPost(ref string, edit models.ThreadPart, removeEmbed bool) (*models.CreateRecordResponse, error) {
+	if length := atproto.GraphemeLength(edit.Text); length > MaxPostLength {
+		return nil, fmt.Errorf("text is %d graphemes long (max %d)", length, MaxPostLength)
+	}
+
+	if err := c.ensureAuthenticated(); err != nil {
+		return nil, err
+	}
+
+	uri, err := c.ownPostURI(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	quoted, err := c.resolveQuote(edit.Quote)
+	if err != nil {
+		return nil, err
+	}
+
+	post := threadPost{
+		text:    edit.Text,
+		images:  edit.Images,
+		video:   edit.Video,
+		linkURL: edit.URL,
+		quoted:  quoted,
+	}
+	embed, err := c.createEmbed(&post)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.conn().recordManager.EditPost(uri, edit.Text, embed, embed != nil || removeEmbed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to edit post %s: %w", uri, err)
+	}
+
+	logger.Infof("Edited post %s, new CID %s", result.URI, result.CID)
+	return result, nil
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/think-root/bluesky-connector/internal/logger"
+	"github.com/think-root/bluesky-connector/internal/models"
+	"github.com/think-root/bluesky-connector/pkg/atproto"
+)
+
+// ErrNotThreadRoot is returned when asked to set reply rules on a reply, as
+// only the root post of a thread takes a threadgate
+var ErrNotThreadRoot = errors.New("reply rules can only be set on the root post of a thread")
+
+// UpdateGates replaces who can reply to the thread a post starts and whether
+// the post can be quoted. A nil replyGate lets everyone reply. Replies only
+// take a postgate, so reply rules for them are rejected
+func (c *BlueSkyClient) UpdateGates(ref string, replyGate *models.ReplyGate, disableQuotes bool) (*models.GatesResponse, error) {
+	if err := c.ensureAuthenticated(); err != nil {
+		return nil, err
+	}
+
+	uri, err := c.ownPostURI(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := c.isReply(uri)
+	if err != nil {
+		return nil, err
+	}
+	if reply && replyGate != nil {
+		return nil, fmt.Errorf("%w: %s is a reply", ErrNotThreadRoot, uri)
+	}
+
+	if !reply {
+		if err := c.conn().recordManager.SetThreadgate(uri, replyGate); err != nil {
+			return nil, fmt.Errorf("failed to update threadgate of %s: %w", uri, err)
+		}
+	}
+	if err := c.conn().recordManager.SetPostgate(uri, disableQuotes); err != nil {
+		return nil, fmt.Errorf("failed to update postgate of %s: %w", uri, err)
+	}
+
+	logger.Infof("Updated gates of %s", uri)
+	return &models.GatesResponse{
+		URI:           uri.String(),
+		ReplyGate:     replyGate,
+		DisableQuotes: disableQuotes,
+	}, nil
+}
+
+// isReply reports whether the post record at uri replies to another post
+func (c *BlueSkyClient) isReply(uri *atproto.ATURI) (bool, error) {
+	record, err := c.conn().recordManager.GetRecord(uri)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch post %s: %w", uri, err)
+	}
+
+	var post struct {
+		Reply *models.Reply `json:"reply"`
+	}
+	if err := json.Unmarshal(record.Value, &post); err != nil {
+		return false, fmt.Errorf("failed to decode post %s: %w", uri, err)
+	}
+
+	return post.Reply != nil, nil
+}
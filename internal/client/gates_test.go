@@ -0,0 +1,62 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/think-root/bluesky-connector/internal/config"
+	"github.com/think-root/bluesky-connector/internal/models"
+	"github.com/think-root/bluesky-connector/pkg/atproto"
+)
+
+func TestUpdateGates_Replies(t *testing.T) {
+	var writes []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == atproto.GetRecordEndpoint && r.URL.Query().Get("collection") != atproto.PostCollection:
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "RecordNotFound"})
+		case r.URL.Path == atproto.GetRecordEndpoint:
+			json.NewEncoder(w).Encode(map[string]any{
+				"uri": "at://did:plc:me/app.bsky.feed.post/3k2b",
+				"cid": "cid",
+				"value": map[string]any{
+					"$type": "app.bsky.feed.post",
+					"text":  "part 2",
+					"reply": map[string]any{
+						"root":   map[string]string{"uri": "at://did:plc:me/app.bsky.feed.post/3k2a", "cid": "rootcid"},
+						"parent": map[string]string{"uri": "at://did:plc:me/app.bsky.feed.post/3k2a", "cid": "rootcid"},
+					},
+				},
+			})
+		default:
+			var body struct {
+				Collection string `json:"collection"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			writes = append(writes, body.Collection)
+			json.NewEncoder(w).Encode(map[string]string{})
+		}
+	}))
+	defer server.Close()
+
+	c := NewBlueSkyClient(&config.Config{}, config.AccountConfig{Name: "default", Handle: "alice.bsky.social", PDSURL: server.URL})
+	c.conn().sessionManager.ResumeSession("access", "refresh")
+	c.setIdentity("did:plc:me", "alice.bsky.social")
+
+	ref := "at://did:plc:me/app.bsky.feed.post/3k2b"
+
+	_, err := c.UpdateGates(ref, &models.ReplyGate{Allow: []string{models.ReplyAllowFollowers}}, true)
+	assert.ErrorIs(t, err, ErrNotThreadRoot)
+	assert.Empty(t, writes)
+
+	// Without reply rules only the postgate of the reply is written
+	result, err := c.UpdateGates(ref, nil, true)
+	require.NoError(t, err)
+	assert.True(t, result.DisableQuotes)
+	assert.Equal(t, []string{atproto.PostgateCollection}, writes)
+}
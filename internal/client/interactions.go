@@ -0,0 +1,129 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/think-root/bluesky-connector/internal/logger"
+	"github.com/think-root/bluesky-connector/internal/models"
+	"github.com/think-root/bluesky-connector/pkg/atproto"
+)
+
+var (
+	ErrNotLiked    = errors.New("post is not liked by this account")
+	ErrNotReposted = errors.New("post is not reposted by this account")
+)
+
+// Like likes a post AT-URI or bsky.app URL. Liking a post the account already
+// likes returns the existing like
+func (c *BlueSkyClient) Like(ref string) (*models.InteractionResponse, error) {
+	return c.interact(ref, atproto.LikeCollection)
+}
+
+// Repost reposts a post AT-URI or bsky.app URL. Reposting a post the account
+// already reposted returns the existing repost
+func (c *BlueSkyClient) Repost(ref string) (*models.InteractionResponse, error) {
+	return c.interact(ref, atproto.RepostCollection)
+}
+
+// Unlike removes the like of a post, given either the post or the AT-URI of
+// the like record
+func (c *BlueSkyClient) Unlike(ref string) (*models.DeletePostResponse, error) {
+	return c.undoInteraction(ref, atproto.LikeCollection)
+}
+
+// Unrepost removes the repost of a post, given either the post or the AT-URI
+// of the repost record
+func (c *BlueSkyClient) Unrepost(ref string) (*models.DeletePostResponse, error) {
+	return c.undoInteraction(ref, atproto.RepostCollection)
+}
+
+func (c *BlueSkyClient) interact(ref, collection string) (*models.InteractionResponse, error) {
+	if err := c.ensureAuthenticated(); err != nil {
+		return nil, err
+	}
+
+	post, err := c.conn().feedManager.GetPost(ref)
+	if err != nil {
+		return nil, err
+	}
+	subject := models.PostRef{URI: post.URI, CID: post.CID}
+
+	if existing := viewerRecord(post, collection); existing != "" {
+		logger.Infof("Post %s already has %s %s", post.URI, collection, existing)
+		return &models.InteractionResponse{URI: existing, Subject: subject, Existing: true}, nil
+	}
+
+	did, _ := c.identity()
+	var created *models.CreateRecordResponse
+	if collection == atproto.LikeCollection {
+		created, err = c.conn().recordManager.Like(did, subject)
+	} else {
+		created, err = c.conn().recordManager.Repost(did, subject)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s for %s: %w", collection, post.URI, err)
+	}
+
+	logger.Infof("Created %s %s for %s", collection, created.URI, post.URI)
+	return &models.InteractionResponse{URI: created.URI, CID: created.CID, Subject: subject}, nil
+}
+
+func (c *BlueSkyClient) undoInteraction(ref, collection string) (*models.DeletePostResponse, error) {
+	if err := c.ensureAuthenticated(); err != nil {
+		return nil, err
+	}
+
+	uri, err := c.interactionRecordURI(ref, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.conn().recordManager.DeleteRecord(uri); err != nil {
+		return nil, fmt.Errorf("failed to delete %s: %w", uri, err)
+	}
+
+	logger.Infof("Deleted %s", uri)
+	return &models.DeletePostResponse{Deleted: []string{uri.String()}}, nil
+}
+
+// interactionRecordURI returns the like or repost record to delete: ref itself
+// when it is one of the account's records of that collection, or else the
+// account's record for the post ref points to
+func (c *BlueSkyClient) interactionRecordURI(ref, collection string) (*atproto.ATURI, error) {
+	if uri, err := atproto.ParseATURI(strings.TrimSpace(ref)); err == nil && uri.Collection == collection {
+		did, handle := c.identity()
+		if uri.Repo != did && uri.Repo != handle {
+			return nil, fmt.Errorf("%w: %s", ErrNotOwnPost, uri)
+		}
+		uri.Repo = did
+		return uri, nil
+	}
+
+	post, err := c.conn().feedManager.GetPost(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := viewerRecord(post, collection)
+	if existing == "" {
+		if collection == atproto.LikeCollection {
+			return nil, fmt.Errorf("%w: %s", ErrNotLiked, post.URI)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrNotReposted, post.URI)
+	}
+
+	return atproto.ParseATURI(existing)
+}
+
+// viewerRecord returns the account's like or repost of the post, if any
+func viewerRecord(post *models.PostView, collection string) string {
+	if post.Viewer == nil {
+		return ""
+	}
+	if collection == atproto.LikeCollection {
+		return post.Viewer.Like
+	}
+	return post.Viewer.Repost
+}
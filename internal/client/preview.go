@@ -0,0 +1,109 @@
+package client
+
+import (
+	"github.com/think-root/bluesky-connector/internal/logger"
+	"github.com/think-root/bluesky-connector/internal/models"
+	"github.com/think-root/bluesky-connector/pkg/atproto"
+)
+
+// PreviewPost returns the records PostWithMedia would write for content
+// without uploading media or creating records. Handles, the quoted post and
+// link card metadata are still looked up, so the facets and embeds match
+// what would be published except for the blob references
+func (c *BlueSkyClient) PreviewPost(content *models.PostContent) (*models.PreviewResponse, error) {
+	if err := c.ensureAuthenticated(); err != nil {
+		return nil, err
+	}
+
+	posts, err := c.planPosts(content)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the first post's reply references are known before publishing
+	reply, err := c.resolveReply(content.ReplyTo)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &models.PreviewResponse{Posts: make([]models.PreviewPost, 0, len(posts))}
+	for i, post := range posts {
+		embed, linkCard := c.previewEmbed(&post)
+		record := c.newRecord(&post, nil, embed)
+		if i == 0 {
+			record.Reply = reply
+		}
+
+		preview.Posts = append(preview.Posts, models.PreviewPost{
+			Record:    record,
+			Graphemes: atproto.GraphemeLength(post.text),
+			Facets:    previewFacets(post.text, record.Facets),
+			LinkCard:  linkCard,
+		})
+	}
+
+	logger.Infof("Previewed %d posts", len(preview.Posts))
+	return preview, nil
+}
+
+// previewEmbed builds the embed createEmbed would, leaving out the blobs it
+// would upload
+func (c *BlueSkyClient) previewEmbed(post *threadPost) (*models.Embed, *models.LinkCard) {
+	var postEmbed *models.Embed
+	var linkCard *models.LinkCard
+
+	switch {
+	case len(post.images) > 0:
+		postEmbed = atproto.PreviewImagesEmbed(post.images)
+
+	case post.video != nil:
+		postEmbed = atproto.PreviewVideoEmbed(*post.video)
+
+	case post.linkURL != "":
+		var thumbURL string
+		postEmbed, thumbURL = atproto.FetchExternalEmbed(post.linkURL)
+		linkCard = &models.LinkCard{
+			URI:         postEmbed.External.URI,
+			Title:       postEmbed.External.Title,
+			Description: postEmbed.External.Description,
+			Image:       thumbURL,
+		}
+	}
+
+	if post.quoted != nil {
+		postEmbed = atproto.CreateQuoteEmbed(post.quoted, postEmbed)
+	}
+
+	return postEmbed, linkCard
+}
+
+// previewFacets describes each facet together with the text it covers
+func previewFacets(text string, facets []models.RichTextFacet) []models.PreviewFacet {
+	var result []models.PreviewFacet
+	for _, facet := range facets {
+		start, end := facet.Index.ByteStart, facet.Index.ByteEnd
+		if start < 0 || end > len(text) || start > end {
+			continue
+		}
+
+		for _, feature := range facet.Features {
+			preview := models.PreviewFacet{
+				Text:      text[start:end],
+				ByteStart: start,
+				ByteEnd:   end,
+			}
+			switch feature.Type {
+			case "app.bsky.richtext.facet#tag":
+				preview.Type, preview.Value = "tag", feature.Tag
+			case "app.bsky.richtext.facet#link":
+				preview.Type, preview.Value = "link", feature.URI
+			case "app.bsky.richtext.facet#mention":
+				preview.Type, preview.Value = "mention", feature.DID
+			default:
+				preview.Type = feature.Type
+			}
+			result = append(result, preview)
+		}
+	}
+	return result
+}
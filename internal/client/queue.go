@@ -0,0 +1,64 @@
+package client
+
+import (
+	"sync"
+	"time"
+
+	"github.com/think-root/bluesky-connector/internal/models"
+)
+
+// publishQueue lets one thread publish at a time, in the order the threads
+// arrived, so concurrent requests never interleave their posts. The zero
+// value is an empty queue
+type publishQueue struct {
+	mu      sync.Mutex
+	busy    bool
+	waiting []chan struct{}
+}
+
+// acquire waits for the turn of the caller, who must release it when done
+// publishing, and reports how many threads were ahead and how long it waited
+func (q *publishQueue) acquire() *models.QueueInfo {
+	start := time.Now()
+
+	q.mu.Lock()
+	if !q.busy {
+		q.busy = true
+		q.mu.Unlock()
+		return &models.QueueInfo{}
+	}
+
+	depth := len(q.waiting) + 1
+	turn := make(chan struct{})
+	q.waiting = append(q.waiting, turn)
+	q.mu.Unlock()
+
+	<-turn
+	return &models.QueueInfo{Depth: depth, WaitMS: time.Since(start).Milliseconds()}
+}
+
+// release hands the turn to the next thread in line
+func (q *publishQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.waiting) == 0 {
+		q.busy = false
+		return
+	}
+
+	next := q.waiting[0]
+	q.waiting = q.waiting[1:]
+	close(next)
+}
+
+// depth is the number of threads publishing or waiting to
+func (q *publishQueue) depth() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.busy {
+		return 0
+	}
+	return len(q.waiting) + 1
+}
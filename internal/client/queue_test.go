@@ -0,0 +1,52 @@
+package client
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/think-root/bluesky-connector/internal/models"
+)
+
+func TestPublishQueue_FIFO(t *testing.T) {
+	var q publishQueue
+	assert.Equal(t, 0, q.depth())
+
+	first := q.acquire()
+	assert.Equal(t, &models.QueueInfo{}, first)
+	assert.Equal(t, 1, q.depth())
+
+	var mu sync.Mutex
+	var order []int
+	infos := make([]*models.QueueInfo, 3)
+
+	var wg sync.WaitGroup
+	for i := range infos {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			info := q.acquire()
+			mu.Lock()
+			order = append(order, i)
+			infos[i] = info
+			mu.Unlock()
+			q.release()
+		}()
+
+		// Queue the goroutines one after another
+		require.Eventually(t, func() bool { return q.depth() == i+2 }, time.Second, time.Millisecond)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	q.release()
+	wg.Wait()
+
+	assert.Equal(t, []int{0, 1, 2}, order)
+	for i, info := range infos {
+		assert.Equal(t, i+1, info.Depth)
+		assert.GreaterOrEqual(t, info.WaitMS, int64(10))
+	}
+	assert.Equal(t, 0, q.depth())
+}
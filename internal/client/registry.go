@@ -0,0 +1,94 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/think-root/bluesky-connector/internal/config"
+	"github.com/think-root/bluesky-connector/internal/logger"
+	"github.com/think-root/bluesky-connector/internal/models"
+)
+
+// ErrUnknownAccount is returned when a request names an account that is not
+// configured
+var ErrUnknownAccount = errors.New("unknown account")
+
+// Registry holds a client, with its own session, for every configured account
+type Registry struct {
+	clients     map[string]*BlueSkyClient
+	names       []string
+	defaultName string
+}
+
+// NewRegistry creates a client for every account in cfg
+func NewRegistry(cfg *config.Config) *Registry {
+	r := &Registry{
+		clients:     make(map[string]*BlueSkyClient, len(cfg.Bluesky.Accounts)),
+		defaultName: cfg.Bluesky.DefaultAccount,
+	}
+
+	for _, account := range cfg.Bluesky.Accounts {
+		r.clients[account.Name] = NewBlueSkyClient(cfg, account)
+		r.names = append(r.names, account.Name)
+	}
+
+	return r
+}
+
+// Client returns the client of the named account, or of the default account
+// when name is empty. Names are case-insensitive
+func (r *Registry) Client(name string) (*BlueSkyClient, error) {
+	if name == "" {
+		name = r.defaultName
+	}
+
+	client, ok := r.clients[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return nil, fmt.Errorf("%w %q, configured accounts: %s", ErrUnknownAccount, name, strings.Join(r.names, ", "))
+	}
+	return client, nil
+}
+
+// AuthenticateAll logs every account in, returning the errors of those that
+// failed by account name
+func (r *Registry) AuthenticateAll() map[string]error {
+	failed := make(map[string]error)
+	for _, name := range r.names {
+		if err := r.clients[name].Authenticate(); err != nil {
+			logger.Errorf("Failed to authenticate account %s: %v", name, err)
+			failed[name] = err
+		}
+	}
+	return failed
+}
+
+// Statuses reports the session of every account, in configuration order
+func (r *Registry) Statuses() []models.AccountStatus {
+	statuses := make([]models.AccountStatus, 0, len(r.names))
+	for _, name := range r.names {
+		status := r.clients[name].Status()
+		status.Default = name == r.defaultName
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// RateLimits reports the rate limit budgets of every account
+func (r *Registry) RateLimits() []models.AccountRateLimits {
+	limits := make([]models.AccountRateLimits, 0, len(r.names))
+	for _, name := range r.names {
+		limits = append(limits, r.clients[name].RateLimits())
+	}
+	return limits
+}
+
+// PostWithMedia publishes content as the account it names, letting the
+// scheduler publish queued posts of every account
+func (r *Registry) PostWithMedia(content *models.PostContent) (*models.CreatePostResponse, error) {
+	client, err := r.Client(content.Account)
+	if err != nil {
+		return nil, err
+	}
+	return client.PostWithMedia(content)
+}
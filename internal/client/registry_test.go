@@ -0,0 +1,43 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/think-root/bluesky-connector/internal/config"
+	"github.com/think-root/bluesky-connector/internal/models"
+)
+
+func TestRegistry(t *testing.T) {
+	cfg := &config.Config{
+		Bluesky: config.BlueSkyConfig{
+			Accounts: []config.AccountConfig{
+				{Name: "project", Handle: "project.bsky.social", AppPassword: "a"},
+				{Name: "personal", Handle: "me.example.com", AppPassword: "b", PDSURL: "https://pds.example.com"},
+			},
+			DefaultAccount: "project",
+		},
+	}
+	registry := NewRegistry(cfg)
+
+	client, err := registry.Client("")
+	require.NoError(t, err)
+	assert.Equal(t, "project", client.AccountName())
+
+	client, err = registry.Client(" Personal ")
+	require.NoError(t, err)
+	assert.Equal(t, "personal", client.AccountName())
+
+	_, err = registry.Client("work")
+	assert.ErrorIs(t, err, ErrUnknownAccount)
+	assert.ErrorContains(t, err, "project, personal")
+
+	_, err = registry.PostWithMedia(&models.PostContent{Text: "Hello", Account: "work"})
+	assert.ErrorIs(t, err, ErrUnknownAccount)
+
+	assert.Equal(t, []models.AccountStatus{
+		{Name: "project", Handle: "project.bsky.social", Default: true},
+		{Name: "personal", Handle: "me.example.com", PDS: "https://pds.example.com"},
+	}, registry.Statuses())
+}
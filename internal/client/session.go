@@ -0,0 +1,99 @@
+package client
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/think-root/bluesky-connector/internal/config"
+	"github.com/think-root/bluesky-connector/internal/logger"
+	"github.com/think-root/bluesky-connector/internal/models"
+	"github.com/think-root/bluesky-connector/internal/sessionstore"
+)
+
+// newSessionStore returns where the account's session is saved, or nil when
+// sessions are not saved
+func newSessionStore(cfg config.SessionConfig, account config.AccountConfig) *sessionstore.Store {
+	if cfg.Dir == "" {
+		return nil
+	}
+
+	secret := cfg.EncryptionKey
+	if secret == "" {
+		secret = account.AppPassword
+	}
+
+	store := sessionstore.New(filepath.Join(cfg.Dir, account.Name+".session"), secret)
+	if err := store.CheckWritable(); err != nil {
+		logger.Warnf("Sessions of account %s will not survive restarts: %v", account.Name, err)
+	}
+	return store
+}
+
+// resumeSession reuses the session saved by an earlier run, refreshing it when
+// the PDS rejects its access token. It reports false when there is no usable
+// session and the account has to log in again
+func (c *BlueSkyClient) resumeSession() bool {
+	if c.sessionStore == nil {
+		return false
+	}
+
+	saved, err := c.sessionStore.Load()
+	if err != nil {
+		logger.Warnf("Ignoring saved session of account %s: %v", c.account.Name, err)
+		return false
+	}
+	if saved == nil || !c.ownsSession(saved) {
+		return false
+	}
+
+	conn := c.connect(saved.PDS)
+	conn.sessionManager.ResumeSession(saved.AccessJWT, saved.RefreshJWT)
+
+	session, err := conn.sessionManager.GetSession()
+	if err != nil {
+		logger.Infof("Saved session of account %s was not accepted (%v), refreshing it", c.account.Name, err)
+
+		refreshed, refreshErr := conn.sessionManager.RefreshSession()
+		if refreshErr != nil {
+			logger.Warnf("Failed to refresh saved session of account %s, logging in again: %v", c.account.Name, refreshErr)
+			c.connect(c.account.PDSURL)
+			return false
+		}
+		session = &models.GetSessionResponse{Handle: refreshed.Handle, DID: refreshed.DID}
+	}
+
+	c.setIdentity(session.DID, session.Handle)
+
+	logger.Infof("Resumed saved session of %s (%s)", session.Handle, session.DID)
+	return true
+}
+
+// ownsSession reports whether a saved session was created for the configured
+// account and PDS, so changing either starts a new one
+func (c *BlueSkyClient) ownsSession(saved *sessionstore.Session) bool {
+	if saved.PDS == "" || !strings.EqualFold(saved.Identifier, c.account.Handle) {
+		return false
+	}
+	return c.account.PDSURL == "" || strings.TrimRight(c.account.PDSURL, "/") == strings.TrimRight(saved.PDS, "/")
+}
+
+// saveSession saves the tokens of a new or refreshed session on pdsURL for
+// the next run
+func (c *BlueSkyClient) saveSession(pdsURL string, session *models.CreateSessionResponse) {
+	if c.sessionStore == nil {
+		return
+	}
+
+	err := c.sessionStore.Save(&sessionstore.Session{
+		Identifier: c.account.Handle,
+		PDS:        pdsURL,
+		DID:        session.DID,
+		AccessJWT:  session.AccessJWT,
+		RefreshJWT: session.RefreshJWT,
+		SavedAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		logger.Warnf("Failed to save session of account %s: %v", c.account.Name, err)
+	}
+}
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/think-root/bluesky-connector/internal/config"
+	"github.com/think-root/bluesky-connector/internal/logger"
+	"github.com/think-root/bluesky-connector/internal/sessionstore"
+	"github.com/think-root/bluesky-connector/pkg/atproto"
+)
+
+func init() {
+	logger.Init("error")
+}
+
+const (
+	savedAccessJWT  = "saved-access-jwt-0123456789"
+	savedRefreshJWT = "saved-refresh-jwt-0123456789"
+)
+
+// sessionServer is a PDS accepting only fresh access tokens, refreshing the
+// saved refresh token unless refreshFails, and counting logins
+func sessionServer(t *testing.T, refreshFails bool, logins *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session := map[string]string{
+			"accessJwt":  "fresh-access-jwt-0123456789",
+			"refreshJwt": "fresh-refresh-jwt-0123456789",
+			"handle":     "alice.bsky.social",
+			"did":        "did:plc:alice",
+		}
+
+		switch r.URL.Path {
+		case atproto.GetSessionEndpoint:
+			if r.Header.Get("Authorization") != "Bearer "+session["accessJwt"] {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "ExpiredToken", "message": "Token has expired"})
+				return
+			}
+			json.NewEncoder(w).Encode(session)
+		case atproto.RefreshSessionEndpoint:
+			assert.Equal(t, "Bearer "+savedRefreshJWT, r.Header.Get("Authorization"))
+			if refreshFails {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "ExpiredToken", "message": "Token has been revoked"})
+				return
+			}
+			json.NewEncoder(w).Encode(session)
+		case atproto.CreateSessionEndpoint:
+			*logins++
+			json.NewEncoder(w).Encode(session)
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	}))
+}
+
+func TestAuthenticate_SavedSession(t *testing.T) {
+	tests := []struct {
+		name         string
+		refreshFails bool
+		wantLogins   int
+	}{
+		{name: "refreshes the saved session", wantLogins: 0},
+		{name: "logs in when refresh fails", refreshFails: true, wantLogins: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logins int
+			server := sessionServer(t, tt.refreshFails, &logins)
+			defer server.Close()
+
+			cfg := &config.Config{Session: config.SessionConfig{Dir: t.TempDir(), EncryptionKey: "key"}}
+			account := config.AccountConfig{Name: "default", Handle: "alice.bsky.social", AppPassword: "pw", PDSURL: server.URL}
+
+			store := newSessionStore(cfg.Session, account)
+			require.NoError(t, store.Save(&sessionstore.Session{
+				Identifier: "alice.bsky.social",
+				PDS:        server.URL,
+				AccessJWT:  savedAccessJWT,
+				RefreshJWT: savedRefreshJWT,
+			}))
+
+			c := NewBlueSkyClient(cfg, account)
+			require.NoError(t, c.Authenticate())
+
+			assert.Equal(t, tt.wantLogins, logins)
+			did, _ := c.identity()
+			assert.Equal(t, "did:plc:alice", did)
+
+			// Either way the new tokens are saved for the next run
+			saved, err := store.Load()
+			require.NoError(t, err)
+			assert.Equal(t, "fresh-refresh-jwt-0123456789", saved.RefreshJWT)
+
+			// A restart reuses them without logging in
+			require.NoError(t, NewBlueSkyClient(cfg, account).Authenticate())
+			assert.Equal(t, tt.wantLogins, logins)
+		})
+	}
+}
+
+func TestOwnsSession(t *testing.T) {
+	c := &BlueSkyClient{account: config.AccountConfig{Handle: "Alice.bsky.social"}}
+	saved := &sessionstore.Session{Identifier: "alice.bsky.social", PDS: "https://pds.example.com"}
+	assert.True(t, c.ownsSession(saved))
+
+	c.account.PDSURL = "https://pds.example.com/"
+	assert.True(t, c.ownsSession(saved))
+
+	c.account.PDSURL = "https://bsky.social"
+	assert.False(t, c.ownsSession(saved))
+
+	c.account = config.AccountConfig{Handle: "bob.bsky.social"}
+	assert.False(t, c.ownsSession(saved))
+}
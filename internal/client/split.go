@@ -0,0 +1,159 @@
+package client
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+	"github.com/think-root/bluesky-connector/pkg/atproto"
+)
+
+var (
+	paragraphBreakRegex = regexp.MustCompile(`\n[ \t]*\n`)
+	sentenceBreakRegex  = regexp.MustCompile(`[.!?…]["'”’)\]]*\s|\n`)
+)
+
+// threadPrefix numbers part i of a thread with total parts, e.g. "🧵 0/2 "
+func threadPrefix(i, total int) string {
+	return fmt.Sprintf("🧵 %d/%d ", i, total-1)
+}
+
+// splitTextIntoParts splits text into thread parts that, once prefixed with
+// threadPrefix, fit into MaxPostLength graphemes. Text that fits into a single
+// post is returned unchanged
+func (c *BlueSkyClient) splitTextIntoParts(text string) []string {
+	if atproto.GraphemeLength(text) <= MaxPostLength {
+		return []string{text}
+	}
+	return splitThread(text, 0)
+}
+
+// splitThread splits text into at least two thread parts, keeping firstReserve
+// graphemes free in the first part. The prefix grows with the number of parts,
+// so the split is repeated until the part count and the prefix agree
+func splitThread(text string, firstReserve int) []string {
+	total := 2
+	for {
+		limit := MaxPostLength - atproto.GraphemeLength(threadPrefix(total-1, total))
+		parts := splitWithLimits(text, limit-firstReserve, limit)
+
+		if len(parts) <= total || len(threadPrefix(len(parts)-1, len(parts))) == len(threadPrefix(total-1, total)) {
+			return parts
+		}
+		total = len(parts)
+	}
+}
+
+// splitWithLimits greedily fills each part up to its grapheme limit, breaking
+// at the best boundary available in order of preference: a paragraph break,
+// the end of a sentence, then any whitespace. URLs, hashtags and mentions
+// never contain whitespace, so they are only cut when a single one is longer
+// than a whole part
+func splitWithLimits(text string, firstLimit, limit int) []string {
+	var parts []string
+	remaining := strings.TrimSpace(text)
+
+	for remaining != "" {
+		partLimit := limit
+		if len(parts) == 0 {
+			partLimit = firstLimit
+		}
+
+		if atproto.GraphemeLength(remaining) <= partLimit {
+			parts = append(parts, remaining)
+			break
+		}
+
+		splitIndex := findSplitIndex(remaining, partLimit, limit)
+		parts = append(parts, strings.TrimSpace(remaining[:splitIndex]))
+		remaining = strings.TrimSpace(remaining[splitIndex:])
+	}
+
+	return parts
+}
+
+// findSplitIndex returns the byte offset at which to end a part of at most
+// partLimit graphemes taken from the start of text
+func findSplitIndex(text string, partLimit, limit int) int {
+	cut := graphemeOffset(text, partLimit)
+	window := text[:cut]
+
+	// Paragraph and sentence breaks are only worth it when they don't leave
+	// the part less than half full
+	minLength := partLimit / 2
+
+	if index := lastBreak(window, paragraphBreakRegex, minLength, false); index > 0 {
+		return index
+	}
+	if index := lastBreak(window, sentenceBreakRegex, minLength, true); index > 0 {
+		return index
+	}
+
+	if next, _ := utf8.DecodeRuneInString(text[cut:]); unicode.IsSpace(next) {
+		return cut
+	}
+
+	wordStart := strings.LastIndexFunc(window, unicode.IsSpace)
+	if wordStart <= 0 {
+		// A single token longer than the whole part has to be cut
+		return cut
+	}
+
+	// When the word cut off at the end has to be broken up anyway because it
+	// doesn't fit into a part of its own, fill this part with its beginning
+	// instead of wasting the space
+	_, size := utf8.DecodeRuneInString(text[wordStart:])
+	word := text[wordStart+size:]
+	if end := strings.IndexFunc(word, unicode.IsSpace); end != -1 {
+		word = word[:end]
+	}
+	if !isProtectedToken(word) && atproto.GraphemeLength(word) > limit {
+		return cut
+	}
+
+	return wordStart
+}
+
+// lastBreak returns the byte offset of the last match of re in window
+// that leaves at least minLength graphemes before it, or -1. When afterMatch
+// is set the part ends after the match (keeping the sentence punctuation),
+// otherwise before it
+func lastBreak(window string, re *regexp.Regexp, minLength int, afterMatch bool) int {
+	matches := re.FindAllStringIndex(window, -1)
+	for i := len(matches) - 1; i >= 0; i-- {
+		index := matches[i][0]
+		if afterMatch {
+			index = matches[i][1]
+		}
+		if atproto.GraphemeLength(window[:index]) < minLength {
+			break
+		}
+		return index
+	}
+	return -1
+}
+
+// graphemeOffset returns the byte offset just past the first n graphemes of text
+func graphemeOffset(text string, n int) int {
+	offset := 0
+	state := -1
+	remaining := text
+	for i := 0; i < n && remaining != ""; i++ {
+		var cluster string
+		cluster, remaining, _, state = uniseg.FirstGraphemeClusterInString(remaining, state)
+		offset += len(cluster)
+	}
+	return offset
+}
+
+// isProtectedToken reports whether a word is a URL, hashtag or mention, which
+// must stay intact whenever possible
+func isProtectedToken(word string) bool {
+	return strings.HasPrefix(word, "http://") ||
+		strings.HasPrefix(word, "https://") ||
+		strings.HasPrefix(word, "#") ||
+		strings.HasPrefix(word, "@")
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/think-root/bluesky-connector/internal/logger"
+	"github.com/think-root/bluesky-connector/internal/models"
+	"github.com/think-root/bluesky-connector/pkg/atproto"
+)
+
+// GetPostStatus looks up the current text, engagement counts and labels of a
+// post AT-URI or bsky.app URL. Deleted and blocked posts are reported as not
+// visible rather than as errors
+func (c *BlueSkyClient) GetPostStatus(ref string) (*models.PostStatusResponse, error) {
+	if err := c.ensureAuthenticated(); err != nil {
+		return nil, err
+	}
+
+	uri, err := c.conn().feedManager.ResolvePostURI(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	thread, err := c.conn().feedManager.GetPostThread(uri.String(), 0)
+
+	var xrpcErr *atproto.XRPCError
+	if errors.As(err, &xrpcErr) && (xrpcErr.Name == "NotFound" || xrpcErr.StatusCode == http.StatusNotFound) {
+		logger.Infof("Post %s not found", uri)
+		return &models.PostStatusResponse{URI: uri.String(), Status: models.PostStatusNotFound}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch post %s: %w", uri, err)
+	}
+
+	if thread.Post == nil {
+		status := models.PostStatusNotFound
+		if thread.Type == "app.bsky.feed.defs#blockedPost" {
+			status = models.PostStatusBlocked
+		}
+		logger.Infof("Post %s is not visible (%s)", uri, thread.Type)
+		return &models.PostStatusResponse{URI: uri.String(), Status: status}, nil
+	}
+
+	return postStatus(thread.Post)
+}
+
+// postStatus summarises the view of a visible post
+func postStatus(post *models.PostView) (*models.PostStatusResponse, error) {
+	var record struct {
+		Text      string `json:"text"`
+		CreatedAt string `json:"createdAt"`
+	}
+	if err := json.Unmarshal(post.Record, &record); err != nil {
+		return nil, fmt.Errorf("failed to decode post %s: %w", post.URI, err)
+	}
+
+	status := &models.PostStatusResponse{
+		URI:     post.URI,
+		CID:     post.CID,
+		Visible: true,
+		Status:  models.PostStatusVisible,
+
+		Author:    post.Author.Handle,
+		Text:      record.Text,
+		CreatedAt: record.CreatedAt,
+		IndexedAt: post.IndexedAt,
+
+		ReplyCount:  post.ReplyCount,
+		RepostCount: post.RepostCount,
+		LikeCount:   post.LikeCount,
+		QuoteCount:  post.QuoteCount,
+	}
+
+	seen := make(map[string]bool)
+	for _, label := range post.Labels {
+		if label.Neg || seen[label.Val] {
+			continue
+		}
+		seen[label.Val] = true
+		status.Labels = append(status.Labels, label.Val)
+	}
+
+	if post.Viewer != nil {
+		status.Like = post.Viewer.Like
+		status.Repost = post.Viewer.Repost
+	}
+
+	return status, nil
+}
@@ -2,28 +2,90 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
+	"github.com/think-root/bluesky-connector/internal/langdetect"
+	"github.com/think-root/bluesky-connector/pkg/atproto"
 )
 
 var (
 	ErrMissingBlueSkyHandle      = errors.New("BLUESKY_HANDLE is required")
 	ErrMissingBlueSkyAppPassword = errors.New("BLUESKY_APP_PASSWORD is required")
 	ErrMissingServerAPIKey       = errors.New("SERVER_API_KEY is required")
+	ErrInvalidHashtagPlacement   = errors.New("BLUESKY_HASHTAG_PLACEMENT must be 'first' or 'last'")
+	ErrInvalidSchedulerInterval  = errors.New("SCHEDULER_INTERVAL must be a positive duration")
+	ErrInvalidIdempotencyTTL     = errors.New("IDEMPOTENCY_TTL must be a positive duration")
+	ErrInvalidRateLimitMaxWait   = fmt.Errorf("BLUESKY_RATE_LIMIT_MAX_WAIT must be between 0s and %v", atproto.MaxRateLimitMaxWait)
+	ErrUnsupportedLangCandidate  = errors.New("BLUESKY_LANG_CANDIDATES contains an unsupported language")
+	ErrDuplicateAccount          = errors.New("account is configured more than once")
+	ErrUnknownDefaultAccount     = errors.New("BLUESKY_DEFAULT_ACCOUNT is not a configured account")
+)
+
+const (
+	HashtagPlacementFirst = "first"
+	HashtagPlacementLast  = "last"
+
+	// DefaultAccountName is the name of the account configured through
+	// BLUESKY_HANDLE and BLUESKY_APP_PASSWORD
+	DefaultAccountName = "default"
 )
 
 type Config struct {
-	Bluesky BlueSkyConfig
-	Server  ServerConfig
-	Log     LogConfig
+	Bluesky     BlueSkyConfig
+	Server      ServerConfig
+	Scheduler   SchedulerConfig
+	Idempotency IdempotencyConfig
+	Session     SessionConfig
+	Log         LogConfig
 }
 
 type BlueSkyConfig struct {
+	// Accounts are the accounts posts can be published as, and
+	// DefaultAccount the name of the one used when a request names none
+	Accounts       []AccountConfig
+	DefaultAccount string
+
+	// PLCDirectoryURL is where did:plc documents are resolved when an
+	// account's PDS is discovered rather than configured
+	PLCDirectoryURL string
+
+	// VideoServiceURL is where videos are uploaded for processing and
+	// VideoServiceAudience the DID its service auth tokens are issued for
+	// (defaults to the did:web of the PDS)
+	VideoServiceURL      string
+	VideoServiceAudience string
+
+	// Hashtags are appended to every post unless a request overrides them,
+	// on the first or last post of a thread depending on HashtagPlacement
+	Hashtags         []string
+	HashtagPlacement string
+
+	// LangDetection tags posts without explicit langs with the language
+	// detected from their text, choosing among LangCandidates (ISO 639-1
+	// codes) when any are set
+	LangDetection  bool
+	LangCandidates []string
+
+	// RateLimitMaxWait is how long a request waits for an exhausted rate
+	// limit to reset before it is rejected instead
+	RateLimitMaxWait time.Duration
+}
+
+// AccountConfig is a Bluesky account requests can select by Name
+type AccountConfig struct {
+	Name        string
 	Handle      string
 	AppPassword string
+
+	// PDSURL is the account's PDS. When empty it is discovered from the
+	// account's DID document
+	PDSURL string
 }
 
 type ServerConfig struct {
@@ -31,6 +93,27 @@ type ServerConfig struct {
 	Port   int
 }
 
+// SchedulerConfig controls the queue of posts scheduled for later
+type SchedulerConfig struct {
+	QueuePath string
+	Interval  time.Duration
+}
+
+// IdempotencyConfig controls how long responses to requests with an
+// Idempotency-Key header are kept for replay
+type IdempotencyConfig struct {
+	StorePath string
+	TTL       time.Duration
+}
+
+// SessionConfig controls where each account's session is saved to survive
+// restarts, in Dir/<account>.session. The file is encrypted with
+// EncryptionKey, or with the account's app password when it is empty
+type SessionConfig struct {
+	Dir           string
+	EncryptionKey string
+}
+
 type LogConfig struct {
 	Level string
 }
@@ -46,15 +129,66 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
+	interval, err := time.ParseDuration(getEnv("SCHEDULER_INTERVAL", "30s"))
+	if err != nil {
+		return nil, err
+	}
+
+	idempotencyTTL, err := time.ParseDuration(getEnv("IDEMPOTENCY_TTL", "24h"))
+	if err != nil {
+		return nil, err
+	}
+
+	langDetection, err := strconv.ParseBool(getEnv("BLUESKY_LANG_DETECTION", "true"))
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimitMaxWait, err := time.ParseDuration(getEnv("BLUESKY_RATE_LIMIT_MAX_WAIT", atproto.DefaultRateLimitMaxWait.String()))
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := loadAccounts()
+	defaultAccount := strings.ToLower(getEnv("BLUESKY_DEFAULT_ACCOUNT", ""))
+	if defaultAccount == "" && len(accounts) > 0 {
+		defaultAccount = accounts[0].Name
+	}
+
 	config := &Config{
 		Bluesky: BlueSkyConfig{
-			Handle:      getEnv("BLUESKY_HANDLE", ""),
-			AppPassword: getEnv("BLUESKY_APP_PASSWORD", ""),
+			Accounts:       accounts,
+			DefaultAccount: defaultAccount,
+
+			PLCDirectoryURL: getEnv("BLUESKY_PLC_DIRECTORY_URL", "https://plc.directory"),
+
+			VideoServiceURL:      getEnv("BLUESKY_VIDEO_SERVICE_URL", "https://video.bsky.app"),
+			VideoServiceAudience: getEnv("BLUESKY_VIDEO_SERVICE_AUD", ""),
+
+			Hashtags:         parseList(getEnvAllowEmpty("BLUESKY_HASHTAGS", "#GitHub #OpenSource")),
+			HashtagPlacement: getEnv("BLUESKY_HASHTAG_PLACEMENT", HashtagPlacementLast),
+
+			LangDetection:  langDetection,
+			LangCandidates: parseList(getEnv("BLUESKY_LANG_CANDIDATES", "")),
+
+			RateLimitMaxWait: rateLimitMaxWait,
 		},
 		Server: ServerConfig{
 			APIKey: getEnv("SERVER_API_KEY", ""),
 			Port:   port,
 		},
+		Scheduler: SchedulerConfig{
+			QueuePath: getEnv("SCHEDULER_QUEUE_PATH", "data/scheduled_posts.json"),
+			Interval:  interval,
+		},
+		Idempotency: IdempotencyConfig{
+			StorePath: getEnv("IDEMPOTENCY_STORE_PATH", "data/idempotency_keys.json"),
+			TTL:       idempotencyTTL,
+		},
+		Session: SessionConfig{
+			Dir:           getEnv("SESSION_STORE_DIR", "data/sessions"),
+			EncryptionKey: getEnv("SESSION_ENCRYPTION_KEY", ""),
+		},
 		Log: LogConfig{
 			Level: getEnv("LOG_LEVEL", "info"),
 		},
@@ -63,6 +197,47 @@ func Load() (*Config, error) {
 	return config, nil
 }
 
+// loadAccounts reads the account of BLUESKY_HANDLE and BLUESKY_APP_PASSWORD,
+// named "default", followed by every account named in BLUESKY_ACCOUNTS, whose
+// settings are read from BLUESKY_ACCOUNT_<NAME>_HANDLE, _APP_PASSWORD and
+// _PDS_URL
+func loadAccounts() []AccountConfig {
+	var accounts []AccountConfig
+
+	if handle, password := getEnv("BLUESKY_HANDLE", ""), getEnv("BLUESKY_APP_PASSWORD", ""); handle != "" || password != "" {
+		accounts = append(accounts, AccountConfig{
+			Name:        DefaultAccountName,
+			Handle:      handle,
+			AppPassword: password,
+			PDSURL:      getEnv("BLUESKY_PDS_URL", ""),
+		})
+	}
+
+	for _, name := range parseList(getEnv("BLUESKY_ACCOUNTS", "")) {
+		prefix := AccountEnvPrefix(name)
+		accounts = append(accounts, AccountConfig{
+			Name:        strings.ToLower(name),
+			Handle:      getEnv(prefix+"HANDLE", ""),
+			AppPassword: getEnv(prefix+"APP_PASSWORD", ""),
+			PDSURL:      getEnv(prefix+"PDS_URL", ""),
+		})
+	}
+
+	return accounts
+}
+
+// AccountEnvPrefix returns the prefix of the variables configuring the named
+// account, e.g. BLUESKY_ACCOUNT_PERSONAL_ for "personal"
+func AccountEnvPrefix(name string) string {
+	key := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(name))
+	return "BLUESKY_ACCOUNT_" + key + "_"
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -70,15 +245,78 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvAllowEmpty is like getEnv but treats a variable that is set to an
+// empty string as an explicit value rather than falling back to the default
+func getEnvAllowEmpty(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// parseList splits a comma or whitespace separated list
+func parseList(value string) []string {
+	return strings.Fields(strings.ReplaceAll(value, ",", " "))
+}
+
 func (c *Config) Validate() error {
-	if c.Bluesky.Handle == "" {
+	if len(c.Bluesky.Accounts) == 0 {
 		return ErrMissingBlueSkyHandle
 	}
-	if c.Bluesky.AppPassword == "" {
-		return ErrMissingBlueSkyAppPassword
+	seen := make(map[string]bool)
+	for _, account := range c.Bluesky.Accounts {
+		if err := account.validate(); err != nil {
+			return err
+		}
+		if seen[account.Name] {
+			return fmt.Errorf("%w: %s", ErrDuplicateAccount, account.Name)
+		}
+		seen[account.Name] = true
+	}
+	if !seen[c.Bluesky.DefaultAccount] {
+		return fmt.Errorf("%w: %s", ErrUnknownDefaultAccount, c.Bluesky.DefaultAccount)
 	}
 	if c.Server.APIKey == "" {
 		return ErrMissingServerAPIKey
 	}
+	if c.Bluesky.HashtagPlacement != HashtagPlacementFirst && c.Bluesky.HashtagPlacement != HashtagPlacementLast {
+		return ErrInvalidHashtagPlacement
+	}
+	if c.Scheduler.Interval <= 0 {
+		return ErrInvalidSchedulerInterval
+	}
+	if c.Idempotency.TTL <= 0 {
+		return ErrInvalidIdempotencyTTL
+	}
+	// A longer wait would run into the timeout of the request it holds up
+	if c.Bluesky.RateLimitMaxWait < 0 || c.Bluesky.RateLimitMaxWait > atproto.MaxRateLimitMaxWait {
+		return ErrInvalidRateLimitMaxWait
+	}
+	for _, code := range c.Bluesky.LangCandidates {
+		if !langdetect.IsSupported(code) {
+			return fmt.Errorf("%w: %s", ErrUnsupportedLangCandidate, code)
+		}
+	}
 	return nil
-}
\ No newline at end of file
+}
+
+func (a AccountConfig) validate() error {
+	if a.Name == DefaultAccountName {
+		if a.Handle == "" {
+			return ErrMissingBlueSkyHandle
+		}
+		if a.AppPassword == "" {
+			return ErrMissingBlueSkyAppPassword
+		}
+		return nil
+	}
+
+	prefix := AccountEnvPrefix(a.Name)
+	if a.Handle == "" {
+		return fmt.Errorf("%sHANDLE is required for account %s", prefix, a.Name)
+	}
+	if a.AppPassword == "" {
+		return fmt.Errorf("%sAPP_PASSWORD is required for account %s", prefix, a.Name)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/think-root/bluesky-connector/internal/client"
+	"github.com/think-root/bluesky-connector/internal/logger"
+	"github.com/think-root/bluesky-connector/internal/middleware"
+)
+
+// ListAccounts lists the configured accounts with the state of their sessions
+func (h *PostHandler) ListAccounts(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"accounts": h.accounts.Statuses(),
+	})
+}
+
+// requestAccount returns the name of the account a request acts as: the
+// account field of its body, else the X-Bluesky-Account header, else the
+// account query parameter. It is empty for the default account
+func requestAccount(c *gin.Context, field string) string {
+	if field != "" {
+		return field
+	}
+	if header := c.GetHeader(middleware.AccountHeader); header != "" {
+		return header
+	}
+	return c.Query("account")
+}
+
+// client returns the client of the account the request acts as, responding
+// with a validation error when it is not configured
+func (h *PostHandler) client(c *gin.Context, field string) (*client.BlueSkyClient, bool) {
+	blueSkyClient, err := h.accounts.Client(requestAccount(c, field))
+	if err != nil {
+		logger.Errorf("Invalid account: %v", err)
+		respondFieldErrors(c, map[string]string{"account": err.Error()})
+		return nil, false
+	}
+	return blueSkyClient, true
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/think-root/bluesky-connector/internal/client"
+	"github.com/think-root/bluesky-connector/internal/config"
+	"github.com/think-root/bluesky-connector/internal/middleware"
+)
+
+func TestUnknownAccount(t *testing.T) {
+	registry := client.NewRegistry(&config.Config{
+		Bluesky: config.BlueSkyConfig{
+			Accounts:       []config.AccountConfig{{Name: "project", Handle: "project.bsky.social", AppPassword: "a"}},
+			DefaultAccount: "project",
+		},
+	})
+	handler := NewPostHandler(registry, nil)
+
+	router := gin.New()
+	router.UseRawPath = true
+	router.UnescapePathValues = true
+	router.POST("/posts/create", handler.CreatePost)
+	router.DELETE("/posts/:uri", handler.DeletePost)
+
+	postURI := "/posts/" + url.PathEscape("at://did:plc:abc/app.bsky.feed.post/3k2a")
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		account string
+	}{
+		{
+			name:   "body field",
+			method: http.MethodPost,
+			path:   "/posts/create",
+			body:   `{"text":"Hello","account":"work"}`,
+		},
+		{
+			name:    "header",
+			method:  http.MethodDelete,
+			path:    postURI,
+			account: "work",
+		},
+		{
+			name:   "query parameter",
+			method: http.MethodDelete,
+			path:   postURI + "?account=work",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.account != "" {
+				req.Header.Set(middleware.AccountHeader, tt.account)
+			}
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var resp struct {
+				Fields map[string]string `json:"fields"`
+			}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, `unknown account "work", configured accounts: project`, resp.Fields["account"])
+		})
+	}
+}
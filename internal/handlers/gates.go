@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/think-root/bluesky-connector/internal/logger"
+	"github.com/think-root/bluesky-connector/internal/models"
+	"github.com/think-root/bluesky-connector/pkg/atproto"
+)
+
+// UpdateGates replaces the reply and quote gates of a published post
+func (h *PostHandler) UpdateGates(c *gin.Context) {
+	uri, ok := postURIParam(c)
+	if !ok {
+		return
+	}
+
+	logger.Infof("Received gates update for %s", uri)
+
+	var req models.UpdateGatesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Errorf("Invalid gates request: %v", err)
+		respondBindingError(c, err)
+		return
+	}
+
+	replyGate, fields := replyGateFromRequest(req.ReplyAllow, req.ReplyLists)
+	if fields != nil {
+		logger.Errorf("Invalid gates request: %v", fields)
+		respondFieldErrors(c, fields)
+		return
+	}
+
+	blueSkyClient, ok := h.client(c, req.Account)
+	if !ok {
+		return
+	}
+
+	result, err := blueSkyClient.UpdateGates(uri, replyGate, req.DisableQuotes)
+	if err != nil {
+		respondClientError(c, "Failed to update gates", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// replyGateFromRequest turns the reply_allow and reply_lists fields into a
+// reply gate, returning nil when both are empty so everyone can reply
+func replyGateFromRequest(allow, lists []string) (*models.ReplyGate, map[string]string) {
+	if len(allow) == 0 && len(lists) == 0 {
+		return nil, nil
+	}
+
+	fields := make(map[string]string)
+
+	if slices.Contains(allow, models.ReplyAllowNobody) && (len(allow) > 1 || len(lists) > 0) {
+		fields["reply_allow"] = "nobody cannot be combined with other rules or lists"
+	}
+	if rules := len(allow) + len(lists); rules > atproto.MaxThreadgateRules {
+		fields["reply_allow"] = fmt.Sprintf("can hold at most %d rules and lists together", atproto.MaxThreadgateRules)
+	}
+
+	for i, list := range lists {
+		uri, err := atproto.ParseATURI(list)
+		if err != nil || uri.Collection != atproto.ListCollection || !uri.HasDID() {
+			fields[fmt.Sprintf("reply_lists[%d]", i)] = "must be the AT-URI of a list, at://<did>/app.bsky.graph.list/<rkey>"
+		}
+	}
+
+	if len(fields) > 0 {
+		return nil, fields
+	}
+
+	gate := &models.ReplyGate{Lists: lists}
+	for _, rule := range allow {
+		if rule != models.ReplyAllowNobody && !slices.Contains(gate.Allow, rule) {
+			gate.Allow = append(gate.Allow, rule)
+		}
+	}
+	return gate, nil
+}
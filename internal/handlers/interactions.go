@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/think-root/bluesky-connector/internal/client"
+	"github.com/think-root/bluesky-connector/internal/logger"
+	"github.com/think-root/bluesky-connector/internal/models"
+	"github.com/think-root/bluesky-connector/pkg/atproto"
+)
+
+// LikePost likes the post whose percent-encoded AT-URI or bsky.app URL is the
+// uri path parameter
+func (h *PostHandler) LikePost(c *gin.Context) {
+	h.interact(c, "like", (*client.BlueSkyClient).Like)
+}
+
+// RepostPost reposts the post in the uri path parameter
+func (h *PostHandler) RepostPost(c *gin.Context) {
+	h.interact(c, "repost", (*client.BlueSkyClient).Repost)
+}
+
+// UnlikePost removes the like of the post in the uri path parameter, which may
+// also be the AT-URI of the like record itself
+func (h *PostHandler) UnlikePost(c *gin.Context) {
+	h.undoInteraction(c, "like", atproto.LikeCollection, (*client.BlueSkyClient).Unlike)
+}
+
+// UnrepostPost removes the repost of the post in the uri path parameter, which
+// may also be the AT-URI of the repost record itself
+func (h *PostHandler) UnrepostPost(c *gin.Context) {
+	h.undoInteraction(c, "repost", atproto.RepostCollection, (*client.BlueSkyClient).Unrepost)
+}
+
+func (h *PostHandler) interact(c *gin.Context, kind string, create func(*client.BlueSkyClient, string) (*models.InteractionResponse, error)) {
+	uri, ok := postURIParam(c)
+	if !ok {
+		return
+	}
+
+	blueSkyClient, ok := h.client(c, "")
+	if !ok {
+		return
+	}
+
+	logger.Infof("Received %s request for %s", kind, uri)
+
+	result, err := create(blueSkyClient, uri)
+	if err != nil {
+		respondClientError(c, "Failed to "+kind+" post", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *PostHandler) undoInteraction(c *gin.Context, kind, collection string, undo func(*client.BlueSkyClient, string) (*models.DeletePostResponse, error)) {
+	uri := c.Param("uri")
+	if !isRecordOf(uri, collection) {
+		if _, err := atproto.ParsePostURI(uri); err != nil {
+			respondFieldErrors(c, map[string]string{
+				"uri": "must be a percent-encoded post AT-URI, bsky.app post URL or " + kind + " AT-URI",
+			})
+			return
+		}
+	}
+
+	blueSkyClient, ok := h.client(c, "")
+	if !ok {
+		return
+	}
+
+	logger.Infof("Received un%s request for %s", kind, uri)
+
+	result, err := undo(blueSkyClient, uri)
+	if err != nil {
+		respondClientError(c, "Failed to un"+kind+" post", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// isRecordOf reports whether uri is the AT-URI of a record in collection
+func isRecordOf(uri, collection string) bool {
+	parsed, err := atproto.ParseATURI(strings.TrimSpace(uri))
+	return err == nil && parsed.Collection == collection
+}
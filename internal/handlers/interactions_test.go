@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/think-root/bluesky-connector/pkg/atproto"
+)
+
+func TestUndoInteraction_RejectsOtherRecords(t *testing.T) {
+	handler := NewPostHandler(nil, nil)
+
+	router := gin.New()
+	router.UseRawPath = true
+	router.UnescapePathValues = true
+	router.DELETE("/posts/:uri/like", handler.UnlikePost)
+	router.DELETE("/posts/:uri/repost", handler.UnrepostPost)
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{
+			name: "repost record on the like route",
+			path: "/posts/" + url.PathEscape("at://did:plc:abc/app.bsky.feed.repost/3k2a") + "/like",
+		},
+		{
+			name: "like record on the repost route",
+			path: "/posts/" + url.PathEscape("at://did:plc:abc/app.bsky.feed.like/3k2a") + "/repost",
+		},
+		{
+			name: "not a reference",
+			path: "/posts/" + url.PathEscape("https://example.com/post/3k2a") + "/like",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, tt.path, nil))
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), "Validation failed")
+		})
+	}
+}
+
+func TestRespondClientError_PostNotFound(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	err := fmt.Errorf("%w: at://did:plc:abc/app.bsky.feed.post/3k2a", atproto.ErrPostNotFound)
+	respondClientError(c, "Failed to like post", err)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"error": "Failed to like post: post not found: at://did:plc:abc/app.bsky.feed.post/3k2a"}`, w.Body.String())
+}
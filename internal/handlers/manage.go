@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/think-root/bluesky-connector/internal/client"
+	"github.com/think-root/bluesky-connector/internal/logger"
+	"github.com/think-root/bluesky-connector/internal/models"
+	"github.com/think-root/bluesky-connector/pkg/atproto"
+)
+
+// GetPostStatus reports whether the post in the uri path parameter is still
+// visible, with its current text, engagement counts and labels
+func (h *PostHandler) GetPostStatus(c *gin.Context) {
+	uri, ok := postURIParam(c)
+	if !ok {
+		return
+	}
+
+	logger.Infof("Received status request for %s", uri)
+
+	blueSkyClient, ok := h.client(c, "")
+	if !ok {
+		return
+	}
+
+	result, err := blueSkyClient.GetPostStatus(uri)
+	if err != nil {
+		respondClientError(c, "Failed to get post status", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// DeletePost deletes the post whose percent-encoded AT-URI or bsky.app URL is
+// the uri path parameter
+func (h *PostHandler) DeletePost(c *gin.Context) {
+	uri, ok := postURIParam(c)
+	if !ok {
+		return
+	}
+
+	logger.Infof("Received delete request for %s", uri)
+
+	blueSkyClient, ok := h.client(c, "")
+	if !ok {
+		return
+	}
+
+	result, err := blueSkyClient.DeletePost(uri)
+	if err != nil {
+		respondClientError(c, "Failed to delete post", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// DeleteThread deletes a thread root and every reply to it that this account
+// published
+func (h *PostHandler) DeleteThread(c *gin.Context) {
+	uri, ok := postURIParam(c)
+	if !ok {
+		return
+	}
+
+	logger.Infof("Received thread delete request for %s", uri)
+
+	blueSkyClient, ok := h.client(c, "")
+	if !ok {
+		return
+	}
+
+	result, err := blueSkyClient.DeleteThread(uri)
+	if err != nil {
+		respondClientError(c, "Failed to delete thread", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// EditPost replaces the text, and optionally the embed, of a published post
+func (h *PostHandler) EditPost(c *gin.Context) {
+	uri, ok := postURIParam(c)
+	if !ok {
+		return
+	}
+
+	logger.Infof("Received edit request for %s", uri)
+
+	var req models.EditPostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Errorf("Invalid edit request: %v", err)
+		respondBindingError(c, err)
+		return
+	}
+
+	payload := models.ThreadPartPayload{
+		Text:   req.Text,
+		Images: req.Images,
+		Video:  req.Video,
+		URL:    req.URL,
+		Quote:  req.Quote,
+	}
+
+	fields := make(map[string]string)
+	validatePart(fields, "", payload)
+	if req.RemoveEmbed && (len(req.Images) > 0 || req.Video != nil || req.URL != "" || req.Quote != "") {
+		fields["remove_embed"] = "cannot be combined with images, video, url or quote"
+	}
+	if len(fields) > 0 {
+		logger.Errorf("Invalid edit request: %v", fields)
+		respondFieldErrors(c, fields)
+		return
+	}
+
+	blueSkyClient, ok := h.client(c, req.Account)
+	if !ok {
+		return
+	}
+
+	edit, err := readPart(payload)
+	if err != nil {
+		logger.Errorf("Failed to read edit media: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to read media: " + err.Error(),
+		})
+		return
+	}
+
+	result, err := blueSkyClient.EditPost(uri, *edit, req.RemoveEmbed)
+	if err != nil {
+		respondClientError(c, "Failed to edit post", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// postURIParam returns the uri path parameter, responding with a validation
+// error when it is not a post reference
+func postURIParam(c *gin.Context) (string, bool) {
+	uri := c.Param("uri")
+	if _, err := atproto.ParsePostURI(uri); err != nil {
+		respondFieldErrors(c, map[string]string{
+			"uri": "must be a percent-encoded post AT-URI or bsky.app post URL",
+		})
+		return "", false
+	}
+	return uri, true
+}
+
+// respondClientError maps an error from the Bluesky client to a response:
+// posts of other accounts are forbidden, reply rules for replies are bad
+// requests, missing posts, likes and reposts are not found, writes that lost
+// a race with another change conflict and anything else is an internal error
+func respondClientError(c *gin.Context, message string, err error) {
+	logger.Errorf("%s: %v", message, err)
+
+	if respondRateLimited(c, message+": "+err.Error(), err) {
+		return
+	}
+
+	status := http.StatusInternalServerError
+	var xrpcErr *atproto.XRPCError
+	switch {
+	case errors.Is(err, client.ErrNotOwnPost):
+		status = http.StatusForbidden
+	case errors.Is(err, client.ErrNotThreadRoot):
+		status = http.StatusBadRequest
+	case errors.Is(err, atproto.ErrPostNotFound), errors.Is(err, client.ErrNotLiked), errors.Is(err, client.ErrNotReposted):
+		status = http.StatusNotFound
+	case errors.As(err, &xrpcErr) && (xrpcErr.StatusCode == http.StatusNotFound || xrpcErr.Name == "NotFound"):
+		status = http.StatusNotFound
+	case errors.As(err, &xrpcErr) && xrpcErr.Name == "InvalidSwap":
+		status = http.StatusConflict
+	}
+
+	c.JSON(status, gin.H{
+		"error": message + ": " + err.Error(),
+	})
+}
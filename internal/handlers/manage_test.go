@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostURIParam_EncodedURIs(t *testing.T) {
+	router := gin.New()
+	router.UseRawPath = true
+	router.UnescapePathValues = true
+
+	var got string
+	capture := func(c *gin.Context) {
+		if uri, ok := postURIParam(c); ok {
+			got = uri
+			c.Status(http.StatusOK)
+		}
+	}
+	router.DELETE("/posts/:uri", capture)
+	router.DELETE("/posts/:uri/thread", capture)
+	router.GET("/posts/:uri", capture)
+	router.DELETE("/posts/scheduled/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	router.GET("/posts/scheduled", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		uri    string
+	}{
+		{
+			name:   "AT-URI",
+			path:   "/posts/" + url.PathEscape("at://did:plc:abc/app.bsky.feed.post/3k2a"),
+			status: http.StatusOK,
+			uri:    "at://did:plc:abc/app.bsky.feed.post/3k2a",
+		},
+		{
+			name:   "bsky.app URL thread",
+			path:   "/posts/" + url.PathEscape("https://bsky.app/profile/alice.bsky.social/post/3k2a") + "/thread",
+			status: http.StatusOK,
+			uri:    "https://bsky.app/profile/alice.bsky.social/post/3k2a",
+		},
+		{
+			name:   "not a post",
+			path:   "/posts/" + url.PathEscape("at://did:plc:abc/app.bsky.feed.like/3k2a"),
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "scheduled post route is kept",
+			path:   "/posts/scheduled/abc123",
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "status of a bsky.app URL",
+			method: http.MethodGet,
+			path:   "/posts/" + url.PathEscape("https://bsky.app/profile/alice.bsky.social/post/3k2a"),
+			status: http.StatusOK,
+			uri:    "https://bsky.app/profile/alice.bsky.social/post/3k2a",
+		},
+		{
+			name:   "scheduled posts list is kept",
+			method: http.MethodGet,
+			path:   "/posts/scheduled",
+			status: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = ""
+			w := httptest.NewRecorder()
+			method := tt.method
+			if method == "" {
+				method = http.MethodDelete
+			}
+			router.ServeHTTP(w, httptest.NewRequest(method, tt.path, nil))
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.uri, got)
+		})
+	}
+}
@@ -1,22 +1,32 @@
 package handlers
 
 import (
+	"encoding/base64"
+	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/think-root/bluesky-connector/internal/client"
 	"github.com/think-root/bluesky-connector/internal/logger"
+	"github.com/think-root/bluesky-connector/internal/middleware"
+	"github.com/think-root/bluesky-connector/internal/models"
+	"github.com/think-root/bluesky-connector/internal/scheduler"
+	"github.com/think-root/bluesky-connector/pkg/atproto"
 )
 
 type PostHandler struct {
-	blueSkyClient *client.BlueSkyClient
+	accounts  *client.Registry
+	scheduler *scheduler.Scheduler
 }
 
-func NewPostHandler(blueSkyClient *client.BlueSkyClient) *PostHandler {
+func NewPostHandler(accounts *client.Registry, scheduler *scheduler.Scheduler) *PostHandler {
 	return &PostHandler{
-		blueSkyClient: blueSkyClient,
+		accounts:  accounts,
+		scheduler: scheduler,
 	}
 }
 
@@ -24,66 +34,355 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 	requestTime := time.Now().Format("2006-01-02 15:04:05")
 	logger.Infof("Received post request at %s", requestTime)
 
-	// Parse form data
-	text := c.PostForm("text")
-	if text == "" {
-		logger.Error("Missing required field: text")
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Text field is required",
+	post, ok := h.bindPost(c)
+	if !ok {
+		return
+	}
+
+	if !post.publishAt.IsZero() {
+		h.schedulePost(c, post.content, post.publishAt, post.timezone)
+		return
+	}
+
+	// Create post
+	result, err := post.client.PostWithMedia(post.content)
+	if err != nil {
+		logger.Errorf("Failed to create post: %v", err)
+		respondPublishError(c, err, result)
+		return
+	}
+
+	logger.Infof("Request completed successfully with %d posts", len(result.Posts))
+	c.JSON(http.StatusOK, result)
+}
+
+// PreviewPost returns the records a create request would write without
+// publishing anything
+func (h *PostHandler) PreviewPost(c *gin.Context) {
+	logger.Info("Received preview request")
+
+	post, ok := h.bindPost(c)
+	if !ok {
+		return
+	}
+
+	result, err := post.client.PreviewPost(post.content)
+	if err != nil {
+		logger.Errorf("Failed to preview post: %v", err)
+		if respondRateLimited(c, err.Error(), err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
 		})
 		return
 	}
 
-	url := c.PostForm("url")
-	
-	logger.Infof("Text content: %s...", truncateString(text, 50))
-	if url != "" {
-		logger.Infof("URL included: %s", url)
+	c.JSON(http.StatusOK, result)
+}
+
+// boundPost is a validated create request with its media read
+type boundPost struct {
+	content *models.PostContent
+
+	// publishAt is zero for posts to publish immediately
+	publishAt time.Time
+	timezone  string
+
+	// client publishes as the account the request selected
+	client *client.BlueSkyClient
+}
+
+// bindPost binds and validates a create request and reads its media. When
+// the request is invalid it responds with the reason and returns false
+func (h *PostHandler) bindPost(c *gin.Context) (*boundPost, bool) {
+	// Bind either multipart/form-data or application/json depending on Content-Type
+	var req models.CreatePostRequest
+	if err := c.ShouldBind(&req); err != nil {
+		logger.Errorf("Invalid post request: %v", err)
+		respondBindingError(c, err)
+		return nil, false
+	}
+
+	logger.Infof("Text content: %s...", truncateString(req.Text, 50))
+	if req.URL != "" {
+		logger.Infof("URL included: %s", req.URL)
+	}
+
+	if fields := validateEmbeds(&req); fields != nil {
+		logger.Errorf("Invalid embeds in request: %v", fields)
+		respondFieldErrors(c, fields)
+		return nil, false
+	}
+
+	replyGate, fields := replyGateFromRequest(req.ReplyAllow, req.ReplyLists)
+	if fields != nil {
+		logger.Errorf("Invalid gates in request: %v", fields)
+		respondFieldErrors(c, fields)
+		return nil, false
+	}
+
+	// Check the publish time before downloading any media
+	post := &boundPost{timezone: req.Timezone}
+	if req.PublishAt != "" {
+		var message string
+		post.publishAt, message = parsePublishAt(req.PublishAt, req.Timezone)
+		if message != "" {
+			logger.Errorf("Invalid publish time %q: %s", req.PublishAt, message)
+			respondFieldErrors(c, map[string]string{"publish_at": message})
+			return nil, false
+		}
+	}
+
+	// Check the account before downloading any media too
+	blueSkyClient, ok := h.client(c, req.Account)
+	if !ok {
+		return nil, false
+	}
+	post.client = blueSkyClient
+
+	images, err := readImages(&req)
+	if err != nil {
+		logger.Errorf("Failed to read image data: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to read image data: " + err.Error(),
+		})
+		return nil, false
+	}
+	if len(images) == 0 {
+		logger.Info("No image in request")
+	}
+
+	video, err := readVideo(&req)
+	if err != nil {
+		logger.Errorf("Failed to read video data: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to read video data: " + err.Error(),
+		})
+		return nil, false
+	}
+
+	post.content = &models.PostContent{
+		Text:   req.Text,
+		URL:    req.URL,
+		Images: images,
+		Video:  video,
+		Quote:  req.Quote,
+
+		Account: post.client.AccountName(),
+		ReplyTo: req.ReplyTo,
+
+		Hashtags:         requestHashtags(&req),
+		HashtagPlacement: req.HashtagPlacement,
+
+		ReplyGate:     replyGate,
+		DisableQuotes: req.DisableQuotes,
+
+		Langs:  req.Langs,
+		Labels: req.Labels,
+	}
+
+	return post, true
+}
+
+// validateEmbeds checks the constraints on attachments that span several
+// request fields: the images must fit into a single images embed, a video
+// cannot be combined with images, every caption file needs a language,
+// labels need media to apply to and the quoted and replied to posts must be
+// post references
+func validateEmbeds(req *models.CreatePostRequest) map[string]string {
+	fields := make(map[string]string)
+
+	imageCount := len(req.ImageFiles) + len(req.Images)
+	if req.Image != nil {
+		imageCount++
+	}
+	if imageCount > models.MaxImagesPerPost {
+		field := "images"
+		if len(req.ImageFiles) > 0 {
+			field = "image"
+		}
+		fields[field] = fmt.Sprintf("must contain at most %d items", models.MaxImagesPerPost)
+	}
+
+	hasVideo := req.Video != nil || req.VideoFile != nil
+	if req.Video != nil && req.VideoFile != nil {
+		fields["video"] = "must be either uploaded as a file or sent as JSON, not both"
+	}
+	if hasVideo && imageCount > 0 {
+		fields["video"] = "cannot be combined with images"
+	}
+	if len(req.CaptionFiles) > 0 && req.VideoFile == nil {
+		fields["caption"] = "requires a video"
+	}
+	if len(req.CaptionLangs) != len(req.CaptionFiles) {
+		fields["caption_lang"] = "must be given once for every caption file"
+	}
+	if len(req.Labels) > 0 && !hasVideo && imageCount == 0 {
+		fields["labels"] = "requires images or a video to label"
+	}
+
+	if req.Quote != "" {
+		if _, err := atproto.ParsePostURI(req.Quote); err != nil {
+			fields["quote"] = "must be a post AT-URI or bsky.app post URL"
+		}
+	}
+
+	if req.ReplyTo != "" {
+		if _, err := atproto.ParsePostURI(req.ReplyTo); err != nil {
+			fields["reply_to"] = "must be a post AT-URI or bsky.app post URL"
+		} else if len(req.ReplyAllow) > 0 || len(req.ReplyLists) > 0 {
+			// Threadgates can only be put on the root post of a thread
+			fields["reply_to"] = "cannot be combined with reply_allow or reply_lists"
+		}
 	}
 
-	// Handle image upload
-	var imageData []byte
-	file, header, err := c.Request.FormFile("image")
-	if err == nil {
-		defer file.Close()
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// requestHashtags returns nil when the request keeps the configured hashtags
+// and an empty slice when it disables them
+func requestHashtags(req *models.CreatePostRequest) []string {
+	switch {
+	case req.NoHashtags:
+		return []string{}
+	case len(req.Hashtags) > 0:
+		return req.Hashtags
+	default:
+		return nil
+	}
+}
+
+// readImages returns the images attached to the request, whether they were
+// uploaded as multipart files, inlined as base64 or referenced by URL
+func readImages(req *models.CreatePostRequest) ([]models.ImageUpload, error) {
+	var images []models.ImageUpload
+
+	for i, header := range req.ImageFiles {
 		logger.Infof("Image included in request: %s", header.Filename)
-		
-		imageData, err = io.ReadAll(file)
+		data, err := readFormFile(header)
+		if err != nil {
+			return nil, fmt.Errorf("image %d: %w", i+1, err)
+		}
+		// Alt texts are matched to files by position
+		var alt string
+		if i < len(req.Alt) {
+			alt = req.Alt[i]
+		}
+		images = append(images, models.ImageUpload{Data: data, Alt: alt})
+	}
+
+	payloads := req.Images
+	if req.Image != nil {
+		payloads = append([]models.ImagePayload{*req.Image}, payloads...)
+	}
+
+	for i, payload := range payloads {
+		data, err := readImagePayload(payload)
+		if err != nil {
+			return nil, fmt.Errorf("image %d: %w", i+1, err)
+		}
+		images = append(images, models.ImageUpload{Data: data, Alt: payload.Alt})
+	}
+
+	return images, nil
+}
+
+// readVideo returns the video attached to the request together with its
+// caption tracks, or nil when the request has no video
+func readVideo(req *models.CreatePostRequest) (*models.VideoUpload, error) {
+	if req.VideoFile != nil {
+		logger.Infof("Video included in request: %s", req.VideoFile.Filename)
+		data, err := readFormFile(req.VideoFile)
 		if err != nil {
-			logger.Errorf("Failed to read image data: %v", err)
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Failed to read image data",
+			return nil, err
+		}
+
+		video := &models.VideoUpload{Data: data, Alt: req.VideoAlt}
+		for i, header := range req.CaptionFiles {
+			captionData, err := readFormFile(header)
+			if err != nil {
+				return nil, fmt.Errorf("caption %d: %w", i+1, err)
+			}
+			video.Captions = append(video.Captions, models.CaptionUpload{
+				Lang: req.CaptionLangs[i],
+				Data: captionData,
 			})
-			return
 		}
-	} else {
-		logger.Info("No image in request")
+		return video, nil
 	}
 
-	// Create post
-	result, err := h.blueSkyClient.PostWithMedia(text, url, imageData)
+	if req.Video == nil {
+		return nil, nil
+	}
+
+	return readVideoPayload(*req.Video)
+}
+
+func readVideoPayload(payload models.VideoPayload) (*models.VideoUpload, error) {
+	var data []byte
+	var err error
+	if payload.URL != "" {
+		logger.Infof("Downloading video from %s", payload.URL)
+		data, _, err = atproto.FetchVideo(payload.URL)
+	} else {
+		logger.Info("Base64 video included in request")
+		data, err = base64.StdEncoding.DecodeString(payload.Data)
+	}
 	if err != nil {
-		logger.Errorf("Failed to create post: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
+		return nil, err
+	}
+
+	video := &models.VideoUpload{Data: data, Alt: payload.Alt}
+	for i, caption := range payload.Captions {
+		captionData, err := base64.StdEncoding.DecodeString(caption.Data)
+		if err != nil {
+			return nil, fmt.Errorf("caption %d: %w", i+1, err)
+		}
+		video.Captions = append(video.Captions, models.CaptionUpload{
+			Lang: caption.Lang,
+			Data: captionData,
 		})
-		return
 	}
+	return video, nil
+}
 
-	logger.Infof("Request completed successfully with %d posts", len(result.Posts))
-	c.JSON(http.StatusOK, result)
+func readFormFile(header *multipart.FileHeader) ([]byte, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+func readImagePayload(payload models.ImagePayload) ([]byte, error) {
+	if payload.URL != "" {
+		logger.Infof("Downloading image from %s", payload.URL)
+		data, _, err := atproto.FetchImage(payload.URL)
+		return data, err
+	}
+
+	logger.Info("Base64 image included in request")
+	return base64.StdEncoding.DecodeString(payload.Data)
 }
 
 func (h *PostHandler) CreateTestPost(c *gin.Context) {
 	logger.Info("Received test post request")
 	
+	blueSkyClient, ok := h.client(c, "")
+	if !ok {
+		return
+	}
+
 	testText := "Test post from Bluesky Connector"
-	result, err := h.blueSkyClient.PostWithMedia(testText, "", nil)
+	result, err := blueSkyClient.PostWithMedia(&models.PostContent{Text: testText})
 	if err != nil {
 		logger.Errorf("Failed to create test post: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+		respondPublishError(c, err, result)
 		return
 	}
 
@@ -91,6 +390,27 @@ func (h *PostHandler) CreateTestPost(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// respondPublishError reports a failed publish with 500, or 429 when it was
+// rate limited, listing the posts published before it failed. Once any post
+// is out, the failure is kept for the idempotency key so a retry cannot
+// publish it again
+func respondPublishError(c *gin.Context, err error, result *models.CreatePostResponse) {
+	body := gin.H{"error": err.Error()}
+	if result != nil && len(result.Posts) > 0 {
+		body["posts"] = result.Posts
+		middleware.MarkPublishing(c)
+	}
+
+	if retryAfter, ok := rateLimitRetryAfter(err); ok {
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		body["retry_after"] = retryAfter
+		c.JSON(http.StatusTooManyRequests, body)
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, body)
+}
+
 func (h *PostHandler) HealthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"status":    "healthy",
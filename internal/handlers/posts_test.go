@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/think-root/bluesky-connector/internal/idempotency"
+	"github.com/think-root/bluesky-connector/internal/logger"
+	"github.com/think-root/bluesky-connector/internal/middleware"
+	"github.com/think-root/bluesky-connector/internal/models"
+	"github.com/think-root/bluesky-connector/pkg/atproto"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	logger.Init("error")
+}
+
+func TestCreatePost_ValidationErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		contentType    string
+		body           string
+		expectedFields map[string]string
+	}{
+		{
+			name:           "JSON missing text",
+			contentType:    "application/json",
+			body:           `{"url":"https://example.com"}`,
+			expectedFields: map[string]string{"text": "is required"},
+		},
+		{
+			name:           "JSON invalid url",
+			contentType:    "application/json",
+			body:           `{"text":"Hello","url":"not a url"}`,
+			expectedFields: map[string]string{"url": "must be a valid URL"},
+		},
+		{
+			name:        "JSON image with both data and url",
+			contentType: "application/json",
+			body:        `{"text":"Hello","image":{"data":"aGVsbG8=","url":"https://example.com/a.png"}}`,
+			expectedFields: map[string]string{
+				"image.data": "must not be set together with url",
+			},
+		},
+		{
+			name:        "JSON image with invalid base64",
+			contentType: "application/json",
+			body:        `{"text":"Hello","image":{"data":"not base64!"}}`,
+			expectedFields: map[string]string{
+				"image.data": "must be valid base64 data",
+			},
+		},
+		{
+			name:        "JSON empty image",
+			contentType: "application/json",
+			body:        `{"text":"Hello","image":{}}`,
+			expectedFields: map[string]string{
+				"image.data": "is required when url is not set",
+			},
+		},
+		{
+			name:        "JSON too many images",
+			contentType: "application/json",
+			body:        `{"text":"Hello","images":[{"data":"aGVsbG8="},{"data":"aGVsbG8="},{"data":"aGVsbG8="},{"data":"aGVsbG8="},{"data":"aGVsbG8="}]}`,
+			expectedFields: map[string]string{
+				"images": "must contain at most 4 items",
+			},
+		},
+		{
+			name:        "JSON single image plus four images",
+			contentType: "application/json",
+			body:        `{"text":"Hello","image":{"data":"aGVsbG8="},"images":[{"data":"aGVsbG8="},{"data":"aGVsbG8="},{"data":"aGVsbG8="},{"data":"aGVsbG8="}]}`,
+			expectedFields: map[string]string{
+				"images": "must contain at most 4 items",
+			},
+		},
+		{
+			name:           "JSON publish_at in the past",
+			contentType:    "application/json",
+			body:           `{"text":"Hello","publish_at":"2020-01-01T10:00:00Z"}`,
+			expectedFields: map[string]string{"publish_at": "must be in the future"},
+		},
+		{
+			name:        "JSON malformed publish_at",
+			contentType: "application/json",
+			body:        `{"text":"Hello","publish_at":"tomorrow"}`,
+			expectedFields: map[string]string{
+				"publish_at": `invalid time "tomorrow", expected RFC 3339 or YYYY-MM-DD HH:MM[:SS]`,
+			},
+		},
+		{
+			name:           "JSON unknown timezone",
+			contentType:    "application/json",
+			body:           `{"text":"Hello","publish_at":"2099-01-01 10:00","timezone":"Mars/Olympus"}`,
+			expectedFields: map[string]string{"timezone": "must be an IANA timezone name"},
+		},
+		{
+			name:        "JSON unknown reply rule",
+			contentType: "application/json",
+			body:        `{"text":"Hello","reply_allow":["friends"]}`,
+			expectedFields: map[string]string{
+				"reply_allow[0]": "must be one of: mentioned followers following nobody",
+			},
+		},
+		{
+			name:        "JSON nobody combined with a list",
+			contentType: "application/json",
+			body:        `{"text":"Hello","reply_allow":["nobody"],"reply_lists":["at://did:plc:abc/app.bsky.graph.list/3k2a"]}`,
+			expectedFields: map[string]string{
+				"reply_allow": "nobody cannot be combined with other rules or lists",
+			},
+		},
+		{
+			name:        "JSON reply list that is not a list",
+			contentType: "application/json",
+			body:        `{"text":"Hello","reply_lists":["at://did:plc:abc/app.bsky.feed.post/3k2a"]}`,
+			expectedFields: map[string]string{
+				"reply_lists[0]": "must be the AT-URI of a list, at://<did>/app.bsky.graph.list/<rkey>",
+			},
+		},
+		{
+			name:           "JSON invalid language tag",
+			contentType:    "application/json",
+			body:           `{"text":"Hello","langs":["en","not a language"]}`,
+			expectedFields: map[string]string{"langs[1]": "must be a BCP-47 language tag"},
+		},
+		{
+			name:        "JSON unknown label",
+			contentType: "application/json",
+			body:        `{"text":"Hello","labels":["gore"],"images":[{"data":"aGVsbG8="}]}`,
+			expectedFields: map[string]string{
+				"labels[0]": "must be one of: sexual nudity porn graphic-media",
+			},
+		},
+		{
+			name:           "JSON labels without media",
+			contentType:    "application/json",
+			body:           `{"text":"Hello","labels":["graphic-media"]}`,
+			expectedFields: map[string]string{"labels": "requires images or a video to label"},
+		},
+		{
+			name:           "JSON invalid reply_to",
+			contentType:    "application/json",
+			body:           `{"text":"Hello","reply_to":"https://example.com/post/1"}`,
+			expectedFields: map[string]string{"reply_to": "must be a post AT-URI or bsky.app post URL"},
+		},
+		{
+			name:        "JSON reply_to with a reply gate",
+			contentType: "application/json",
+			body:        `{"text":"Hello","reply_to":"at://did:plc:abc/app.bsky.feed.post/3k2a","reply_allow":["followers"]}`,
+			expectedFields: map[string]string{
+				"reply_to": "cannot be combined with reply_allow or reply_lists",
+			},
+		},
+		{
+			name:           "Form missing text",
+			contentType:    "application/x-www-form-urlencoded",
+			body:           "url=https://example.com",
+			expectedFields: map[string]string{"text": "is required"},
+		},
+	}
+
+	handler := NewPostHandler(nil, nil)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/bluesky/api/posts/create", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", tt.contentType)
+
+			handler.CreatePost(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var resp struct {
+				Error  string            `json:"error"`
+				Fields map[string]string `json:"fields"`
+			}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, "Validation failed", resp.Error)
+			assert.Equal(t, tt.expectedFields, resp.Fields)
+		})
+	}
+}
+
+func TestCreatePost_MalformedJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/bluesky/api/posts/create", strings.NewReader(`{"text":`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	NewPostHandler(nil, nil).CreatePost(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid request body")
+}
+
+func TestRespondPublishError(t *testing.T) {
+	partial := &models.CreatePostResponse{Posts: []models.CreateRecordResponse{{URI: "at://did:plc:me/app.bsky.feed.post/1", CID: "cid1"}}}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	respondPublishError(c, errors.New("failed to create post 2: boom"), partial)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.JSONEq(t, `{
+		"error": "failed to create post 2: boom",
+		"posts": [{"uri": "at://did:plc:me/app.bsky.feed.post/1", "cid": "cid1"}]
+	}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	respondPublishError(c, &atproto.RateLimitError{Host: "bsky.social", RetryAfter: time.Minute}, nil)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error": "rate limited by bsky.social, retry in 1m0s", "retry_after": 60}`, w.Body.String())
+}
+
+func TestRespondPublishError_IdempotencyKey(t *testing.T) {
+	store, err := idempotency.NewStore(filepath.Join(t.TempDir(), "keys.json"), time.Hour)
+	require.NoError(t, err)
+
+	var calls int
+	var result *models.CreatePostResponse
+	router := gin.New()
+	router.POST("/posts/create", middleware.IdempotencyMiddleware(store), func(c *gin.Context) {
+		calls++
+		respondPublishError(c, &atproto.RateLimitError{Host: "bsky.social", RetryAfter: time.Minute}, result)
+	})
+
+	send := func(key string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/posts/create", strings.NewReader(`{"text":"hello"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Nothing was published, so the key is free for the retry Retry-After asks for
+	assert.Equal(t, http.StatusTooManyRequests, send("key-1").Code)
+	retry := send("key-1")
+	assert.Equal(t, http.StatusTooManyRequests, retry.Code)
+	assert.Empty(t, retry.Header().Get(middleware.IdempotentReplayedHeader))
+	assert.Equal(t, 2, calls)
+
+	// Once a post is out, the failure is replayed instead of publishing again
+	result = &models.CreatePostResponse{Posts: []models.CreateRecordResponse{{URI: "at://did:plc:me/app.bsky.feed.post/1", CID: "cid1"}}}
+	first := send("key-2")
+	assert.Equal(t, http.StatusTooManyRequests, first.Code)
+	retry = send("key-2")
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "true", retry.Header().Get(middleware.IdempotentReplayedHeader))
+	assert.Equal(t, 3, calls)
+}
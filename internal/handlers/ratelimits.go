@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/think-root/bluesky-connector/pkg/atproto"
+)
+
+// RateLimits lists the rate limit budgets Bluesky last reported for every
+// account
+func (h *PostHandler) RateLimits(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"accounts": h.accounts.RateLimits(),
+	})
+}
+
+// respondRateLimited responds with 429 Too Many Requests and a Retry-After
+// header when err is a rate limit, reporting whether it did
+func respondRateLimited(c *gin.Context, message string, err error) bool {
+	retryAfter, ok := rateLimitRetryAfter(err)
+	if !ok {
+		return false
+	}
+
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       message,
+		"retry_after": retryAfter,
+	})
+	return true
+}
+
+// rateLimitRetryAfter returns the seconds to wait before retrying when err is
+// a rate limit
+func rateLimitRetryAfter(err error) (int, bool) {
+	var rateLimitErr *atproto.RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		return 0, false
+	}
+	return int(math.Ceil(rateLimitErr.RetryAfter.Seconds())), true
+}
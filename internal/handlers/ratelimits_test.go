@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/think-root/bluesky-connector/pkg/atproto"
+)
+
+func TestRespondClientError_RateLimited(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	err := fmt.Errorf("failed to create like: %w", &atproto.RateLimitError{Host: "bsky.social", RetryAfter: 41500 * time.Millisecond})
+	respondClientError(c, "Failed to like post", err)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "42", w.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{
+		"error": "Failed to like post: failed to create like: rate limited by bsky.social, retry in 42s",
+		"retry_after": 42
+	}`, w.Body.String())
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/think-root/bluesky-connector/internal/logger"
+	"github.com/think-root/bluesky-connector/internal/models"
+	"github.com/think-root/bluesky-connector/internal/scheduler"
+)
+
+// schedulePost queues content for publishing at publishAt and responds with
+// the scheduled post
+func (h *PostHandler) schedulePost(c *gin.Context, content *models.PostContent, publishAt time.Time, timezone string) {
+	post, err := h.scheduler.Schedule(*content, publishAt, timezone)
+	if err != nil {
+		respondSchedulerError(c, "Failed to schedule post", err)
+		return
+	}
+
+	logger.Infof("Scheduled post %s for %s", post.ID, post.PublishAt.Format(time.RFC3339))
+	c.JSON(http.StatusAccepted, post)
+}
+
+// ListScheduledPosts lists the queued posts, optionally filtered by status
+func (h *PostHandler) ListScheduledPosts(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", models.ScheduledStatusPending, models.ScheduledStatusPublishing,
+		models.ScheduledStatusPublished, models.ScheduledStatusFailed:
+	default:
+		respondFieldErrors(c, map[string]string{
+			"status": "must be one of: pending publishing published failed",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"posts": h.scheduler.Store().List(status),
+	})
+}
+
+func (h *PostHandler) GetScheduledPost(c *gin.Context) {
+	post, err := h.scheduler.Store().Get(c.Param("id"))
+	if err != nil {
+		respondSchedulerError(c, "Failed to get scheduled post", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, post)
+}
+
+// ReschedulePost moves a pending post to a new publish time
+func (h *PostHandler) ReschedulePost(c *gin.Context) {
+	var req models.RescheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Errorf("Invalid reschedule request: %v", err)
+		respondBindingError(c, err)
+		return
+	}
+
+	publishAt, message := parsePublishAt(req.PublishAt, req.Timezone)
+	if message != "" {
+		respondFieldErrors(c, map[string]string{"publish_at": message})
+		return
+	}
+
+	post, err := h.scheduler.Reschedule(c.Param("id"), publishAt, req.Timezone)
+	if err != nil {
+		respondSchedulerError(c, "Failed to reschedule post", err)
+		return
+	}
+
+	logger.Infof("Rescheduled post %s for %s", post.ID, post.PublishAt.Format(time.RFC3339))
+	c.JSON(http.StatusOK, post)
+}
+
+// CancelScheduledPost removes a pending post from the queue
+func (h *PostHandler) CancelScheduledPost(c *gin.Context) {
+	post, err := h.scheduler.Store().Cancel(c.Param("id"))
+	if err != nil {
+		respondSchedulerError(c, "Failed to cancel scheduled post", err)
+		return
+	}
+
+	logger.Infof("Cancelled scheduled post %s", post.ID)
+	c.JSON(http.StatusOK, post)
+}
+
+// parsePublishAt parses a requested publish time, returning a validation
+// message instead of the time when it is malformed or not in the future
+func parsePublishAt(value, timezone string) (time.Time, string) {
+	publishAt, err := scheduler.ParsePublishAt(value, timezone)
+	if err != nil {
+		return time.Time{}, err.Error()
+	}
+	if !publishAt.After(time.Now()) {
+		return time.Time{}, "must be in the future"
+	}
+	return publishAt, ""
+}
+
+func respondSchedulerError(c *gin.Context, message string, err error) {
+	logger.Errorf("%s: %v", message, err)
+
+	switch {
+	case errors.Is(err, scheduler.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, scheduler.ErrNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, scheduler.ErrPublishAtInPast):
+		respondFieldErrors(c, map[string]string{"publish_at": "must be in the future"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message + ": " + err.Error()})
+	}
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/think-root/bluesky-connector/internal/client"
+	"github.com/think-root/bluesky-connector/internal/logger"
+	"github.com/think-root/bluesky-connector/internal/models"
+	"github.com/think-root/bluesky-connector/pkg/atproto"
+)
+
+// CreateThread publishes a thread whose parts are written by the caller
+func (h *PostHandler) CreateThread(c *gin.Context) {
+	logger.Info("Received thread request")
+
+	var req models.CreateThreadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Errorf("Invalid thread request: %v", err)
+		respondBindingError(c, err)
+		return
+	}
+
+	// Check every part before anything is downloaded or published
+	if fields := validateThreadParts(req.Parts); fields != nil {
+		logger.Errorf("Invalid thread parts: %v", fields)
+		respondFieldErrors(c, fields)
+		return
+	}
+
+	if req.ReplyTo != "" {
+		if _, err := atproto.ParsePostURI(req.ReplyTo); err != nil {
+			respondFieldErrors(c, map[string]string{"reply_to": "must be a post AT-URI or bsky.app post URL"})
+			return
+		}
+	}
+
+	blueSkyClient, ok := h.client(c, req.Account)
+	if !ok {
+		return
+	}
+
+	parts, err := readThreadParts(req.Parts)
+	if err != nil {
+		logger.Errorf("Failed to read thread media: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to read media: " + err.Error(),
+		})
+		return
+	}
+
+	result, err := blueSkyClient.PostThread(parts, req.Langs, req.ReplyTo)
+	if err != nil {
+		logger.Errorf("Failed to create thread: %v", err)
+		respondPublishError(c, err, result)
+		return
+	}
+
+	logger.Infof("Thread request completed successfully with %d posts", len(result.Posts))
+	c.JSON(http.StatusOK, result)
+}
+
+// validateThreadParts checks the length of every part and that each one
+// embeds at most one kind of media
+func validateThreadParts(parts []models.ThreadPartPayload) map[string]string {
+	fields := make(map[string]string)
+
+	for i, part := range parts {
+		validatePart(fields, fmt.Sprintf("parts[%d].", i), part)
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// validatePart adds the problems with a single post to fields, prefixing the
+// field names with prefix
+func validatePart(fields map[string]string, prefix string, part models.ThreadPartPayload) {
+	if length := atproto.GraphemeLength(part.Text); length > client.MaxPostLength {
+		fields[prefix+"text"] = fmt.Sprintf("must be at most %d graphemes long, got %d", client.MaxPostLength, length)
+	}
+
+	media := 0
+	for _, present := range []bool{len(part.Images) > 0, part.Video != nil, part.URL != ""} {
+		if present {
+			media++
+		}
+	}
+	if media > 1 {
+		fields[prefix+"images"] = "only one of images, video and url can be set per part"
+	}
+	if len(part.Labels) > 0 && len(part.Images) == 0 && part.Video == nil {
+		fields[prefix+"labels"] = "requires images or a video to label"
+	}
+
+	if part.Quote != "" {
+		if _, err := atproto.ParsePostURI(part.Quote); err != nil {
+			fields[prefix+"quote"] = "must be a post AT-URI or bsky.app post URL"
+		}
+	}
+}
+
+func readThreadParts(payloads []models.ThreadPartPayload) ([]models.ThreadPart, error) {
+	parts := make([]models.ThreadPart, 0, len(payloads))
+
+	for i, payload := range payloads {
+		part, err := readPart(payload)
+		if err != nil {
+			return nil, fmt.Errorf("part %d %w", i+1, err)
+		}
+		parts = append(parts, *part)
+	}
+
+	return parts, nil
+}
+
+// readPart downloads or decodes the media of a single post
+func readPart(payload models.ThreadPartPayload) (*models.ThreadPart, error) {
+	part := &models.ThreadPart{
+		Text:   payload.Text,
+		URL:    payload.URL,
+		Quote:  payload.Quote,
+		Labels: payload.Labels,
+	}
+
+	for i, imagePayload := range payload.Images {
+		data, err := readImagePayload(imagePayload)
+		if err != nil {
+			return nil, fmt.Errorf("image %d: %w", i+1, err)
+		}
+		part.Images = append(part.Images, models.ImageUpload{Data: data, Alt: imagePayload.Alt})
+	}
+
+	if payload.Video != nil {
+		video, err := readVideoPayload(*payload.Video)
+		if err != nil {
+			return nil, fmt.Errorf("video: %w", err)
+		}
+		part.Video = video
+	}
+
+	return part, nil
+}
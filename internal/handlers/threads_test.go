@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateThread_ValidationErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectedFields map[string]string
+	}{
+		{
+			name:           "Missing parts",
+			body:           `{}`,
+			expectedFields: map[string]string{"parts": "is required"},
+		},
+		{
+			name:           "Part without text",
+			body:           `{"parts":[{"text":"First"},{"url":"https://example.com"}]}`,
+			expectedFields: map[string]string{"parts[1].text": "is required"},
+		},
+		{
+			name: "Part too long",
+			body: `{"parts":[{"text":"First"},{"text":"` + strings.Repeat("ї", 301) + `"}]}`,
+			expectedFields: map[string]string{
+				"parts[1].text": "must be at most 300 graphemes long, got 301",
+			},
+		},
+		{
+			name: "Part with images and link card",
+			body: `{"parts":[{"text":"First","url":"https://example.com","images":[{"data":"aGVsbG8="}]}]}`,
+			expectedFields: map[string]string{
+				"parts[0].images": "only one of images, video and url can be set per part",
+			},
+		},
+		{
+			name: "Part with invalid quote",
+			body: `{"parts":[{"text":"First","quote":"https://example.com/post/1"}]}`,
+			expectedFields: map[string]string{
+				"parts[0].quote": "must be a post AT-URI or bsky.app post URL",
+			},
+		},
+		{
+			name:           "Invalid reply_to",
+			body:           `{"parts":[{"text":"First"}],"reply_to":"not a post"}`,
+			expectedFields: map[string]string{"reply_to": "must be a post AT-URI or bsky.app post URL"},
+		},
+		{
+			name: "Part labelled without media",
+			body: `{"parts":[{"text":"First","labels":["graphic-media"]}]}`,
+			expectedFields: map[string]string{
+				"parts[0].labels": "requires images or a video to label",
+			},
+		},
+	}
+
+	handler := NewPostHandler(nil, nil)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/bluesky/api/posts/thread", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			handler.CreateThread(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var resp struct {
+				Error  string            `json:"error"`
+				Fields map[string]string `json:"fields"`
+			}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, "Validation failed", resp.Error)
+			assert.Equal(t, tt.expectedFields, resp.Fields)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// Report validation errors using the request field names clients send
+	// (json or form tag) instead of the Go struct field names
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			for _, tag := range []string{"json", "form"} {
+				name := strings.Split(field.Tag.Get(tag), ",")[0]
+				if name != "" && name != "-" {
+					return name
+				}
+			}
+			return field.Name
+		})
+	}
+}
+
+// respondBindingError writes a 400 response describing why the request body
+// could not be bound, listing every invalid field when validation failed
+func respondBindingError(c *gin.Context, err error) {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make(map[string]string, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields[fieldPath(fe)] = fieldErrorMessage(fe)
+		}
+		respondFieldErrors(c, fields)
+		return
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		err = fmt.Errorf("malformed JSON at offset %d", syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		err = fmt.Errorf("field %s must be of type %s", typeErr.Field, typeErr.Type)
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error": "Invalid request body: " + err.Error(),
+	})
+}
+
+// respondFieldErrors writes a 400 response mapping each invalid field to the
+// reason it was rejected
+func respondFieldErrors(c *gin.Context, fields map[string]string) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":  "Validation failed",
+		"fields": fields,
+	})
+}
+
+// fieldPath strips the top-level struct name from the validator namespace,
+// e.g. "CreatePostRequest.image.data" becomes "image.data"
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.Index(ns, "."); i != -1 {
+		return ns[i+1:]
+	}
+	return ns
+}
+
+func fieldErrorMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "required_without":
+		return fmt.Sprintf("is required when %s is not set", strings.ToLower(fe.Param()))
+	case "excluded_with":
+		return fmt.Sprintf("must not be set together with %s", strings.ToLower(fe.Param()))
+	case "url":
+		return "must be a valid URL"
+	case "base64":
+		return "must be valid base64 data"
+	case "bcp47_language_tag":
+		return "must be a BCP-47 language tag"
+	case "timezone":
+		return "must be an IANA timezone name"
+	case "min":
+		return fmt.Sprintf("must contain at least %s items", fe.Param())
+	case "max":
+		return fmt.Sprintf("must contain at most %s items", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", fe.Param())
+	default:
+		return fmt.Sprintf("failed %s validation", fe.Tag())
+	}
+}
@@ -0,0 +1,154 @@
+package idempotency
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/think-root/bluesky-connector/internal/fsutil"
+)
+
+var (
+	ErrInProgress          = errors.New("a request with this idempotency key is still being processed")
+	ErrFingerprintMismatch = errors.New("idempotency key was already used with a different request")
+)
+
+// Record is the stored outcome of a request made with an idempotency key
+type Record struct {
+	Fingerprint string    `json:"fingerprint"`
+	StatusCode  int       `json:"status_code"`
+	ContentType string    `json:"content_type"`
+	Body        []byte    `json:"body"`
+	ExpiresAt   time.Time `json:"expires_at"`
+
+	// inProgress records reserve a key while its first request runs and are
+	// never persisted, so a crash releases them
+	inProgress bool
+}
+
+// Store remembers the responses to requests made with an idempotency key for
+// ttl, mirroring them to a JSON file so they survive restarts
+type Store struct {
+	path string
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	records map[string]*Record
+}
+
+// NewStore loads the keys saved at path, dropping the expired ones
+func NewStore(path string, ttl time.Duration) (*Store, error) {
+	s := &Store{
+		path:    path,
+		ttl:     ttl,
+		records: make(map[string]*Record),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read idempotency keys: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &s.records); err != nil {
+		return nil, fmt.Errorf("failed to parse idempotency keys: %w", err)
+	}
+
+	now := time.Now()
+	for key, record := range s.records {
+		if now.After(record.ExpiresAt) {
+			delete(s.records, key)
+		}
+	}
+
+	return s, nil
+}
+
+// Begin reserves key for a request with the given fingerprint. It returns the
+// stored record when the same request already completed, ErrFingerprintMismatch
+// when the key was used for a different request, ErrInProgress while the first
+// request is still running, and nil when the caller should process the request
+// and then call Complete or Release
+func (s *Store) Begin(key, fingerprint string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if record, ok := s.records[key]; ok && time.Now().Before(record.ExpiresAt) {
+		switch {
+		case record.Fingerprint != fingerprint:
+			return nil, ErrFingerprintMismatch
+		case record.inProgress:
+			return nil, ErrInProgress
+		default:
+			copied := *record
+			return &copied, nil
+		}
+	}
+
+	s.records[key] = &Record{
+		Fingerprint: fingerprint,
+		ExpiresAt:   time.Now().Add(s.ttl),
+		inProgress:  true,
+	}
+	return nil, nil
+}
+
+// Complete stores the response to the request that reserved key
+func (s *Store) Complete(key string, statusCode int, contentType string, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[key]
+	if !ok {
+		return fmt.Errorf("idempotency key %q was not reserved", key)
+	}
+
+	record.StatusCode = statusCode
+	record.ContentType = contentType
+	record.Body = body
+	record.ExpiresAt = time.Now().Add(s.ttl)
+	record.inProgress = false
+
+	return s.save()
+}
+
+// Release frees a reserved key so the request can be retried with it
+func (s *Store) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if record, ok := s.records[key]; ok && record.inProgress {
+		delete(s.records, key)
+	}
+}
+
+// save writes the completed, unexpired records to disk. Callers must hold s.mu
+func (s *Store) save() error {
+	now := time.Now()
+	completed := make(map[string]*Record, len(s.records))
+	for key, record := range s.records {
+		if now.After(record.ExpiresAt) {
+			delete(s.records, key)
+			continue
+		}
+		if !record.inProgress {
+			completed[key] = record
+		}
+	}
+
+	data, err := json.Marshal(completed)
+	if err != nil {
+		return fmt.Errorf("failed to encode idempotency keys: %w", err)
+	}
+
+	if err := fsutil.WriteFileAtomic(s.path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save idempotency keys: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package idempotency
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_ReplaysCompletedRequests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+
+	store, err := NewStore(path, time.Hour)
+	require.NoError(t, err)
+
+	record, err := store.Begin("key-1", "fp")
+	require.NoError(t, err)
+	assert.Nil(t, record)
+
+	_, err = store.Begin("key-1", "fp")
+	assert.ErrorIs(t, err, ErrInProgress)
+
+	require.NoError(t, store.Complete("key-1", 200, "application/json", []byte(`{"posts":[]}`)))
+
+	// The stored response survives a restart
+	reloaded, err := NewStore(path, time.Hour)
+	require.NoError(t, err)
+
+	record, err = reloaded.Begin("key-1", "fp")
+	require.NoError(t, err)
+	require.NotNil(t, record)
+	assert.Equal(t, 200, record.StatusCode)
+	assert.Equal(t, "application/json", record.ContentType)
+	assert.Equal(t, `{"posts":[]}`, string(record.Body))
+
+	_, err = reloaded.Begin("key-1", "other")
+	assert.ErrorIs(t, err, ErrFingerprintMismatch)
+}
+
+func TestStore_ReleaseAllowsRetry(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "keys.json"), time.Hour)
+	require.NoError(t, err)
+
+	_, err = store.Begin("key-1", "fp")
+	require.NoError(t, err)
+	store.Release("key-1")
+
+	// A failed request does not pin the key to its payload either
+	record, err := store.Begin("key-1", "other")
+	require.NoError(t, err)
+	assert.Nil(t, record)
+}
+
+func TestStore_ExpiredKeysAreForgotten(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+
+	store, err := NewStore(path, time.Millisecond)
+	require.NoError(t, err)
+
+	_, err = store.Begin("key-1", "fp")
+	require.NoError(t, err)
+	require.NoError(t, store.Complete("key-1", 200, "application/json", []byte(`{}`)))
+
+	time.Sleep(5 * time.Millisecond)
+
+	record, err := store.Begin("key-1", "other")
+	require.NoError(t, err)
+	assert.Nil(t, record)
+
+	reloaded, err := NewStore(path, time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, reloaded.records)
+}
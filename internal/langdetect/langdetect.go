@@ -0,0 +1,76 @@
+package langdetect
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/abadojack/whatlanggo"
+)
+
+// nonWordRegex matches URLs, hashtags and mentions, which say little about
+// the language of the text around them
+var nonWordRegex = regexp.MustCompile(`https?://\S+|[#@]\S+`)
+
+// Detector guesses the language of a text offline from its trigrams
+type Detector struct {
+	options whatlanggo.Options
+}
+
+// New creates a detector that only considers the given ISO 639-1 languages,
+// or every supported language when candidates is empty. Narrowing the
+// candidates makes short texts far more reliable. Unsupported codes are
+// ignored
+func New(candidates []string) *Detector {
+	d := &Detector{}
+
+	for _, code := range candidates {
+		if lang, ok := lookup(code); ok {
+			if d.options.Whitelist == nil {
+				d.options.Whitelist = make(map[whatlanggo.Lang]bool)
+			}
+			d.options.Whitelist[lang] = true
+		}
+	}
+
+	return d
+}
+
+// IsSupported reports whether the ISO 639-1 code is a language the detector
+// can recognise
+func IsSupported(code string) bool {
+	_, ok := lookup(code)
+	return ok
+}
+
+// Detect returns the BCP-47 tag of the language text is written in, or nil
+// when it cannot be told reliably
+func (d *Detector) Detect(text string) []string {
+	text = strings.TrimSpace(nonWordRegex.ReplaceAllString(text, " "))
+	if text == "" {
+		return nil
+	}
+
+	info := whatlanggo.DetectWithOptions(text, d.options)
+
+	// With a single candidate every text is classified as it, so only the
+	// script check is meaningful
+	if !info.IsReliable() && len(d.options.Whitelist) != 1 {
+		return nil
+	}
+
+	code := info.Lang.Iso6391()
+	if code == "" {
+		return nil
+	}
+	return []string{code}
+}
+
+func lookup(code string) (whatlanggo.Lang, bool) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	for lang := range whatlanggo.Langs {
+		if lang.Iso6391() == code {
+			return lang, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,50 @@
+package langdetect
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []string
+		text       string
+		expected   []string
+	}{
+		{
+			name:     "English",
+			text:     "A new release of our open source command line tool is out, with faster builds and better error messages.",
+			expected: []string{"en"},
+		},
+		{
+			name:     "Ukrainian",
+			text:     "Вийшла нова версія нашого інструменту з відкритим кодом: швидша збірка та зрозуміліші повідомлення про помилки.",
+			expected: []string{"uk"},
+		},
+		{
+			name:       "Ukrainian against Russian with candidates",
+			candidates: []string{"uk", "en"},
+			text:       "Привіт, світе! Це наш новий проєкт.",
+			expected:   []string{"uk"},
+		},
+		{
+			name:     "links, hashtags and mentions are ignored",
+			text:     "https://github.com/think-root #GitHub #OpenSource @alice.bsky.social",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, New(tt.candidates).Detect(tt.text))
+		})
+	}
+}
+
+func TestIsSupported(t *testing.T) {
+	assert.True(t, IsSupported("uk"))
+	assert.True(t, IsSupported("EN"))
+	assert.False(t, IsSupported("xx"))
+}
@@ -0,0 +1,212 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/think-root/bluesky-connector/internal/idempotency"
+	"github.com/think-root/bluesky-connector/internal/logger"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	maxIdempotencyMemoryBytes = 32 << 20
+
+	publishingContextKey = "idempotency.publishing"
+)
+
+// IdempotencyMiddleware makes requests carrying an Idempotency-Key header safe
+// to retry: the response to the first request is stored and replayed for
+// every repeat with the same payload instead of running the handler again.
+// Failed requests are not stored, so they can be retried, unless the handler
+// called MarkPublishing because some posts were already published; then the
+// response is kept whatever its status, since a retry would publish them twice
+func IdempotencyMiddleware(store *idempotency.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength),
+			})
+			return
+		}
+
+		fingerprint, err := requestFingerprint(c.Request)
+		if err != nil {
+			// Let the handler report the malformed body
+			logger.Warnf("Could not fingerprint request with idempotency key %s: %v", key, err)
+			c.Next()
+			return
+		}
+
+		record, err := store.Begin(key, fingerprint)
+		switch {
+		case errors.Is(err, idempotency.ErrFingerprintMismatch):
+			logger.Warnf("Idempotency key %s reused with a different payload", key)
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, idempotency.ErrInProgress):
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case record != nil:
+			logger.Infof("Replaying response for idempotency key %s", key)
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(record.StatusCode, record.ContentType, record.Body)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		// Deferred so a panicking handler does not leave the key in progress
+		finished := false
+		defer func() {
+			if finished {
+				return
+			}
+			if !c.GetBool(publishingContextKey) {
+				store.Release(key)
+				return
+			}
+			body, _ := json.Marshal(gin.H{"error": "request failed after publishing posts"})
+			if err := store.Complete(key, http.StatusInternalServerError, "application/json; charset=utf-8", body); err != nil {
+				logger.Errorf("Failed to store response for idempotency key %s: %v", key, err)
+			}
+		}()
+
+		c.Next()
+		finished = true
+
+		status := recorder.Status()
+		if (status < 200 || status >= 300) && !c.GetBool(publishingContextKey) {
+			store.Release(key)
+			return
+		}
+
+		if err := store.Complete(key, status, recorder.Header().Get("Content-Type"), recorder.body.Bytes()); err != nil {
+			logger.Errorf("Failed to store response for idempotency key %s: %v", key, err)
+		}
+	}
+}
+
+// MarkPublishing records that the handler has published at least one post,
+// after which the response is stored for the idempotency key even when it is
+// an error
+func MarkPublishing(c *gin.Context) {
+	c.Set(publishingContextKey, true)
+}
+
+// responseRecorder keeps a copy of the response body as it is written
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
+
+// requestFingerprint hashes what a request asks for rather than its exact
+// bytes: JSON bodies are compared after normalising key order and whitespace,
+// and form bodies by their sorted fields and file contents, since multipart
+// boundaries change on every retry. The account the request selects through
+// its header or query counts as part of the request
+func requestFingerprint(req *http.Request) (string, error) {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s?%s\n", req.Method, req.URL.Path, req.URL.Query().Encode())
+	fmt.Fprintf(hash, "account %q\n", req.Header.Get(AccountHeader))
+
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	switch mediaType {
+	case "multipart/form-data", "application/x-www-form-urlencoded":
+		if err := req.ParseMultipartForm(maxIdempotencyMemoryBytes); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+			return "", err
+		}
+		if err := hashForm(hash, req); err != nil {
+			return "", err
+		}
+
+	default:
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return "", err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		var payload any
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return "", err
+		}
+		// encoding/json writes map keys in sorted order
+		normalized, err := json.Marshal(payload)
+		if err != nil {
+			return "", err
+		}
+		hash.Write(normalized)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func hashForm(hash io.Writer, req *http.Request) error {
+	keys := make([]string, 0, len(req.PostForm))
+	for key := range req.PostForm {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Fprintf(hash, "field %q %q\n", key, req.PostForm[key])
+	}
+
+	if req.MultipartForm == nil {
+		return nil
+	}
+
+	keys = keys[:0]
+	for key := range req.MultipartForm.File {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, header := range req.MultipartForm.File[key] {
+			file, err := header.Open()
+			if err != nil {
+				return err
+			}
+			fileHash := sha256.New()
+			_, err = io.Copy(fileHash, file)
+			file.Close()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(hash, "file %q %x\n", key, fileHash.Sum(nil))
+		}
+	}
+
+	return nil
+}
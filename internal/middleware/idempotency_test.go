@@ -0,0 +1,193 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/think-root/bluesky-connector/internal/idempotency"
+	"github.com/think-root/bluesky-connector/internal/logger"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	logger.Init("error")
+}
+
+// newIdempotentRouter returns a router whose handler counts its calls and
+// echoes the text it was sent. It fails when the text is "fail" and panics on
+// "panic", both after having published posts when the text is prefixed with
+// "publish-"
+func newIdempotentRouter(t *testing.T, calls *int) *gin.Engine {
+	store, err := idempotency.NewStore(filepath.Join(t.TempDir(), "keys.json"), time.Hour)
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(gin.RecoveryWithWriter(io.Discard))
+	router.POST("/posts/create", IdempotencyMiddleware(store), func(c *gin.Context) {
+		*calls++
+		text := c.PostForm("text")
+		if text == "" {
+			var req struct {
+				Text string `json:"text"`
+			}
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			text = req.Text
+		}
+		if published, ok := strings.CutPrefix(text, "publish-"); ok {
+			MarkPublishing(c)
+			text = published
+		}
+		if text == "panic" {
+			panic("handler failed")
+		}
+		if text == "fail" {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"text": text, "call": *calls})
+	})
+	return router
+}
+
+func postJSON(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/posts/create", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyMiddleware_ReplaysSameRequest(t *testing.T) {
+	var calls int
+	router := newIdempotentRouter(t, &calls)
+
+	first := postJSON(router, "key-1", `{"text":"hello","url":"https://example.com"}`)
+	assert.Equal(t, http.StatusOK, first.Code)
+
+	// Key order and whitespace do not make it a different request
+	second := postJSON(router, "key-1", `{ "url": "https://example.com", "text": "hello" }`)
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "true", second.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 1, calls)
+
+	other := postJSON(router, "key-1", `{"text":"something else"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, other.Code)
+	assert.Equal(t, 1, calls)
+
+	postJSON(router, "", `{"text":"hello"}`)
+	postJSON(router, "", `{"text":"hello"}`)
+	assert.Equal(t, 3, calls)
+}
+
+func TestIdempotencyMiddleware_FailuresCanBeRetried(t *testing.T) {
+	var calls int
+	router := newIdempotentRouter(t, &calls)
+
+	assert.Equal(t, http.StatusInternalServerError, postJSON(router, "key-1", `{"text":"fail"}`).Code)
+	assert.Equal(t, http.StatusInternalServerError, postJSON(router, "key-1", `{"text":"fail"}`).Code)
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyMiddleware_FailuresAfterPublishingAreKept(t *testing.T) {
+	var calls int
+	router := newIdempotentRouter(t, &calls)
+
+	first := postJSON(router, "key-1", `{"text":"publish-fail"}`)
+	assert.Equal(t, http.StatusInternalServerError, first.Code)
+
+	second := postJSON(router, "key-1", `{"text":"publish-fail"}`)
+	assert.Equal(t, http.StatusInternalServerError, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "true", second.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotencyMiddleware_Panics(t *testing.T) {
+	var calls int
+	router := newIdempotentRouter(t, &calls)
+
+	// A panic before publishing frees the key for a retry
+	assert.Equal(t, http.StatusInternalServerError, postJSON(router, "key-1", `{"text":"panic"}`).Code)
+	assert.Equal(t, http.StatusInternalServerError, postJSON(router, "key-1", `{"text":"panic"}`).Code)
+	assert.Equal(t, 2, calls)
+
+	// A panic after posts were published keeps it, so a retry cannot publish twice
+	assert.Equal(t, http.StatusInternalServerError, postJSON(router, "key-2", `{"text":"publish-panic"}`).Code)
+	retry := postJSON(router, "key-2", `{"text":"publish-panic"}`)
+	assert.Equal(t, http.StatusInternalServerError, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+	assert.Contains(t, retry.Body.String(), "request failed after publishing posts")
+	assert.Equal(t, 3, calls)
+}
+
+func TestIdempotencyMiddleware_AccountIsPartOfRequest(t *testing.T) {
+	var calls int
+	router := newIdempotentRouter(t, &calls)
+
+	assert.Equal(t, http.StatusOK, postJSON(router, "key-1", `{"text":"hello"}`).Code)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/posts/create", strings.NewReader(`{"text":"hello"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IdempotencyKeyHeader, "key-1")
+	req.Header.Set(AccountHeader, "personal")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotencyMiddleware_MultipartIgnoresBoundary(t *testing.T) {
+	var calls int
+	router := newIdempotentRouter(t, &calls)
+
+	send := func(image string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		require.NoError(t, writer.WriteField("text", "hello"))
+		part, err := writer.CreateFormFile("image", "image.png")
+		require.NoError(t, err)
+		_, err = part.Write([]byte(image))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/posts/create", &body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, send("image-bytes").Code)
+	assert.Equal(t, http.StatusOK, send("image-bytes").Code)
+	assert.Equal(t, 1, calls)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, send("other-bytes").Code)
+}
+
+func TestIdempotencyMiddleware_RejectsLongKeys(t *testing.T) {
+	var calls int
+	router := newIdempotentRouter(t, &calls)
+
+	w := postJSON(router, strings.Repeat("k", maxIdempotencyKeyLength+1), `{"text":"hello"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, 0, calls)
+}
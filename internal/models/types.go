@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"mime/multipart"
+	"time"
+)
 
 // AT Protocol Session types
 type CreateSessionRequest struct {
@@ -15,6 +19,11 @@ type CreateSessionResponse struct {
 	DID        string `json:"did"`
 }
 
+type GetSessionResponse struct {
+	Handle string `json:"handle"`
+	DID    string `json:"did"`
+}
+
 // AT Protocol Record types
 type CreateRecordRequest struct {
 	Repo       string `json:"repo"`
@@ -35,6 +44,35 @@ type PostRecord struct {
 	Reply     *Reply          `json:"reply,omitempty"`
 	Embed     *Embed          `json:"embed,omitempty"`
 	Facets    []RichTextFacet `json:"facets,omitempty"`
+	Langs     []string        `json:"langs,omitempty"`
+	Labels    *SelfLabels     `json:"labels,omitempty"`
+}
+
+// SubjectRecord is an app.bsky.feed.like or app.bsky.feed.repost record,
+// pointing at the post it likes or reposts
+type SubjectRecord struct {
+	Type      string    `json:"$type"`
+	Subject   PostRef   `json:"subject"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Self-label values a post can carry as a content warning
+const (
+	LabelSexual       = "sexual"
+	LabelNudity       = "nudity"
+	LabelPorn         = "porn"
+	LabelGraphicMedia = "graphic-media"
+)
+
+// SelfLabels are content warnings the author puts on their own record
+// (com.atproto.label.defs#selfLabels)
+type SelfLabels struct {
+	Type   string      `json:"$type"`
+	Values []SelfLabel `json:"values"`
+}
+
+type SelfLabel struct {
+	Val string `json:"val"`
 }
 
 // RichTextFacet represents a rich text annotation (link, mention, hashtag)
@@ -71,6 +109,32 @@ type Embed struct {
 	Type     string         `json:"$type"`
 	Images   []EmbedImage   `json:"images,omitempty"`
 	External *EmbedExternal `json:"external,omitempty"`
+
+	// app.bsky.embed.video fields
+	Video       *BlobRef       `json:"video,omitempty"`
+	Captions    []EmbedCaption `json:"captions,omitempty"`
+	Alt         string         `json:"alt,omitempty"`
+	AspectRatio *AspectRatio   `json:"aspectRatio,omitempty"`
+
+	// app.bsky.embed.record and app.bsky.embed.recordWithMedia fields
+	Record *EmbedRecord `json:"record,omitempty"`
+	Media  *Embed       `json:"media,omitempty"`
+}
+
+// EmbedRecord is the quoted record of an embed. In app.bsky.embed.record it is
+// the strong reference itself (URI and CID), while app.bsky.embed.recordWithMedia
+// nests a whole app.bsky.embed.record whose reference is in Record
+type EmbedRecord struct {
+	Type   string   `json:"$type,omitempty"`
+	URI    string   `json:"uri,omitempty"`
+	CID    string   `json:"cid,omitempty"`
+	Record *PostRef `json:"record,omitempty"`
+}
+
+// EmbedCaption is a WebVTT caption track attached to a video embed
+type EmbedCaption struct {
+	Lang string   `json:"lang"`
+	File *BlobRef `json:"file"`
 }
 
 type EmbedExternal struct {
@@ -81,8 +145,15 @@ type EmbedExternal struct {
 }
 
 type EmbedImage struct {
-	Alt   string   `json:"alt"`
-	Image *BlobRef `json:"image"`
+	Alt         string       `json:"alt"`
+	Image       *BlobRef     `json:"image"`
+	AspectRatio *AspectRatio `json:"aspectRatio,omitempty"`
+}
+
+// AspectRatio lets clients reserve layout space for media before it loads
+type AspectRatio struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
 }
 
 // BlobRef represents a reference to a blob in the AT Protocol
@@ -125,16 +196,533 @@ type BlobUploadResponse struct {
 	Blob BlobRef `json:"blob"`
 }
 
+// Identity types
+type ResolveHandleResponse struct {
+	DID string `json:"did"`
+}
+
+// DIDDocument is the part of a DID document that locates an account's PDS
+type DIDDocument struct {
+	ID          string       `json:"id"`
+	AlsoKnownAs []string     `json:"alsoKnownAs,omitempty"`
+	Service     []DIDService `json:"service,omitempty"`
+}
+
+type DIDService struct {
+	ID              string `json:"id"`
+	Type            string `json:"type"`
+	ServiceEndpoint string `json:"serviceEndpoint"`
+}
+
+// Feed types
+type ProfileViewBasic struct {
+	DID         string `json:"did"`
+	Handle      string `json:"handle"`
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+type PostView struct {
+	URI       string           `json:"uri"`
+	CID       string           `json:"cid"`
+	Author    ProfileViewBasic `json:"author"`
+	Record    json.RawMessage  `json:"record"`
+	IndexedAt string           `json:"indexedAt"`
+	Viewer    *PostViewerState `json:"viewer,omitempty"`
+
+	ReplyCount  int     `json:"replyCount"`
+	RepostCount int     `json:"repostCount"`
+	LikeCount   int     `json:"likeCount"`
+	QuoteCount  int     `json:"quoteCount"`
+	Labels      []Label `json:"labels,omitempty"`
+}
+
+// Label is a com.atproto.label.defs#label put on a record by its author or a
+// labeler service (Src)
+type Label struct {
+	Src string `json:"src"`
+	URI string `json:"uri"`
+	Val string `json:"val"`
+	Neg bool   `json:"neg,omitempty"`
+}
+
+// PostViewerState holds the AT-URIs of the authenticated account's like and
+// repost of a post, empty when it has none
+type PostViewerState struct {
+	Like   string `json:"like,omitempty"`
+	Repost string `json:"repost,omitempty"`
+}
+
+type GetPostsResponse struct {
+	Posts []PostView `json:"posts"`
+}
+
+// ThreadViewPost is a node of an app.bsky.feed.getPostThread response. Post
+// is nil for replies that are deleted or blocked
+type ThreadViewPost struct {
+	Type    string           `json:"$type"`
+	Post    *PostView        `json:"post,omitempty"`
+	Replies []ThreadViewPost `json:"replies,omitempty"`
+}
+
+type GetPostThreadResponse struct {
+	Thread ThreadViewPost `json:"thread"`
+}
+
+type GetRecordResponse struct {
+	URI   string          `json:"uri"`
+	CID   string          `json:"cid"`
+	Value json.RawMessage `json:"value"`
+}
+
+// PutRecordRequest replaces a record. SwapRecord is the CID the record must
+// still have for the write to succeed
+type PutRecordRequest struct {
+	Repo       string `json:"repo"`
+	Collection string `json:"collection"`
+	RKey       string `json:"rkey"`
+	Record     any    `json:"record"`
+	SwapRecord string `json:"swapRecord,omitempty"`
+}
+
+// ThreadgateRecord is an app.bsky.feed.threadgate record. It shares its record
+// key with the root post it gates. A nil Allow lets everyone reply and an
+// empty one nobody
+type ThreadgateRecord struct {
+	Type          string            `json:"$type"`
+	Post          string            `json:"post"`
+	Allow         *[]ThreadgateRule `json:"allow,omitempty"`
+	CreatedAt     time.Time         `json:"createdAt"`
+	HiddenReplies []string          `json:"hiddenReplies,omitempty"`
+}
+
+type ThreadgateRule struct {
+	Type string `json:"$type"`
+	List string `json:"list,omitempty"`
+}
+
+// PostgateRecord is an app.bsky.feed.postgate record. It shares its record
+// key with the post it gates
+type PostgateRecord struct {
+	Type                  string         `json:"$type"`
+	Post                  string         `json:"post"`
+	CreatedAt             time.Time      `json:"createdAt"`
+	EmbeddingRules        []PostgateRule `json:"embeddingRules,omitempty"`
+	DetachedEmbeddingURIs []string       `json:"detachedEmbeddingUris,omitempty"`
+}
+
+type PostgateRule struct {
+	Type string `json:"$type"`
+}
+
+type DeleteRecordRequest struct {
+	Repo       string `json:"repo"`
+	Collection string `json:"collection"`
+	RKey       string `json:"rkey"`
+}
+
+// Video service types
+type ServiceAuthResponse struct {
+	Token string `json:"token"`
+}
+
+// VideoJobStatus tracks the processing of an uploaded video. Blob is set once
+// State reaches JOB_STATE_COMPLETED
+type VideoJobStatus struct {
+	JobID    string   `json:"jobId"`
+	DID      string   `json:"did"`
+	State    string   `json:"state"`
+	Progress int      `json:"progress,omitempty"`
+	Blob     *BlobRef `json:"blob,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	Message  string   `json:"message,omitempty"`
+}
+
+type GetJobStatusResponse struct {
+	JobStatus VideoJobStatus `json:"jobStatus"`
+}
+
 // API Request/Response types
+
+// CreatePostRequest is accepted either as multipart/form-data (with the image
+// uploaded as a file) or as application/json (with the image inlined as
+// base64 data or referenced by URL)
+//
+// Multipart requests may repeat the image field up to MaxImagesPerPost times,
+// with the alt field repeated in the same order to describe each image
 type CreatePostRequest struct {
-	Text  string `form:"text" binding:"required"`
-	URL   string `form:"url"`
-	Image []byte `form:"image"`
+	Text       string                  `json:"text" form:"text" binding:"required"`
+	URL        string                  `json:"url" form:"url" binding:"omitempty,url"`
+	Image      *ImagePayload           `json:"image" form:"-"`
+	Images     []ImagePayload          `json:"images" form:"-" binding:"max=4,dive"`
+	ImageFiles []*multipart.FileHeader `json:"-" form:"image"`
+	Alt        []string                `json:"-" form:"alt"`
+
+	Video        *VideoPayload           `json:"video" form:"-"`
+	VideoFile    *multipart.FileHeader   `json:"-" form:"video"`
+	VideoAlt     string                  `json:"-" form:"video_alt"`
+	CaptionFiles []*multipart.FileHeader `json:"-" form:"caption"`
+	CaptionLangs []string                `json:"-" form:"caption_lang"`
+
+	// Quote is the AT-URI or bsky.app URL of a post to quote
+	Quote string `json:"quote" form:"quote"`
+
+	// ReplyTo is the AT-URI or bsky.app URL of a post to publish the text
+	// as a reply to, continuing its thread
+	ReplyTo string `json:"reply_to" form:"reply_to"`
+
+	// Hashtags replace the configured hashtags for this post, NoHashtags
+	// disables them and HashtagPlacement overrides the configured placement
+	Hashtags         []string `json:"hashtags" form:"hashtags"`
+	NoHashtags       bool     `json:"no_hashtags" form:"no_hashtags"`
+	HashtagPlacement string   `json:"hashtag_placement" form:"hashtag_placement" binding:"omitempty,oneof=first last"`
+
+	// PublishAt queues the post instead of publishing it immediately. A time
+	// without a UTC offset is read in Timezone (an IANA name, UTC by default)
+	PublishAt string `json:"publish_at" form:"publish_at"`
+	Timezone  string `json:"timezone" form:"timezone" binding:"omitempty,timezone"`
+
+	// ReplyAllow takes "mentioned", "followers" and "following", or "nobody"
+	// on its own, and ReplyLists AT-URIs of lists whose members can reply.
+	// Leaving both empty lets everyone reply
+	ReplyAllow    []string `json:"reply_allow" form:"reply_allow" binding:"dive,oneof=mentioned followers following nobody"`
+	ReplyLists    []string `json:"reply_lists" form:"reply_lists"`
+	DisableQuotes bool     `json:"disable_quotes" form:"disable_quotes"`
+
+	// Langs are the BCP-47 tags of the languages the post is written in.
+	// When empty they are detected from the text
+	Langs []string `json:"langs" form:"langs" binding:"max=3,dive,bcp47_language_tag"`
+
+	// Labels are content warnings put on the post carrying the images or video
+	Labels []string `json:"labels" form:"labels" binding:"dive,oneof=sexual nudity porn graphic-media"`
+
+	// Account is the name of the configured account to publish as
+	Account string `json:"account" form:"account"`
+}
+
+// UpdateGatesRequest replaces who can reply to a thread and whether a post
+// can be quoted, with the same fields as CreatePostRequest
+type UpdateGatesRequest struct {
+	ReplyAllow    []string `json:"reply_allow" binding:"dive,oneof=mentioned followers following nobody"`
+	ReplyLists    []string `json:"reply_lists"`
+	DisableQuotes bool     `json:"disable_quotes"`
+	Account       string   `json:"account"`
+}
+
+// ImagePayload is an image supplied in a JSON request, either as base64
+// encoded data or as a URL to download it from
+type ImagePayload struct {
+	Data string `json:"data" binding:"required_without=URL,excluded_with=URL,omitempty,base64"`
+	URL  string `json:"url" binding:"omitempty,url"`
+	Alt  string `json:"alt"`
+}
+
+// VideoPayload is a video supplied in a JSON request, either as base64
+// encoded data or as a URL to download it from
+type VideoPayload struct {
+	Data     string           `json:"data" binding:"required_without=URL,excluded_with=URL,omitempty,base64"`
+	URL      string           `json:"url" binding:"omitempty,url"`
+	Alt      string           `json:"alt"`
+	Captions []CaptionPayload `json:"captions" binding:"max=20,dive"`
+}
+
+// CaptionPayload is a base64 encoded WebVTT caption track for a video
+type CaptionPayload struct {
+	Lang string `json:"lang" binding:"required,bcp47_language_tag"`
+	Data string `json:"data" binding:"required,base64"`
+}
+
+// MaxImagesPerPost is the most images an app.bsky.embed.images embed can hold
+const MaxImagesPerPost = 4
+
+// PostContent is a post request with its media resolved, ready to be published
+type PostContent struct {
+	Text   string        `json:"text"`
+	URL    string        `json:"url,omitempty"`
+	Images []ImageUpload `json:"images,omitempty"`
+	Video  *VideoUpload  `json:"video,omitempty"`
+	Quote  string        `json:"quote,omitempty"`
+
+	// Account is the name of the account to publish as, empty for the
+	// default account
+	Account string `json:"account,omitempty"`
+
+	// ReplyTo is empty to start a new thread
+	ReplyTo string `json:"reply_to,omitempty"`
+
+	// Hashtags is nil to use the configured hashtags and empty to post none
+	Hashtags         []string `json:"hashtags"`
+	HashtagPlacement string   `json:"hashtag_placement,omitempty"`
+
+	// ReplyGate is nil to let everyone reply to the thread
+	ReplyGate     *ReplyGate `json:"reply_gate,omitempty"`
+	DisableQuotes bool       `json:"disable_quotes,omitempty"`
+
+	// Langs is nil to detect the language from the text
+	Langs []string `json:"langs,omitempty"`
+
+	// Labels are put on the post carrying the images or video
+	Labels []string `json:"labels,omitempty"`
+}
+
+// Reply gate rules
+const (
+	ReplyAllowMentioned = "mentioned"
+	ReplyAllowFollowers = "followers"
+	ReplyAllowFollowing = "following"
+	ReplyAllowNobody    = "nobody"
+)
+
+// ReplyGate limits who can reply to a thread to the accounts matching any of
+// its rules: those mentioned in the root post, the author's followers, the
+// accounts the author follows and the members of Lists. A gate without rules
+// lets nobody reply
+type ReplyGate struct {
+	Allow []string `json:"allow,omitempty"`
+	Lists []string `json:"lists,omitempty"`
+}
+
+// ImageUpload is a decoded image and the alt text describing it
+type ImageUpload struct {
+	Data []byte `json:"data"`
+	Alt  string `json:"alt,omitempty"`
+}
+
+// VideoUpload is a decoded video with its alt text and caption tracks
+type VideoUpload struct {
+	Data     []byte          `json:"data"`
+	Alt      string          `json:"alt,omitempty"`
+	Captions []CaptionUpload `json:"captions,omitempty"`
+}
+
+// CaptionUpload is a WebVTT caption track in the given language
+type CaptionUpload struct {
+	Lang string `json:"lang"`
+	Data []byte `json:"data"`
+}
+
+// CreateThreadRequest publishes each part as its own post of a thread,
+// without splitting, numbering or adding hashtags. Langs applies to every
+// part and is detected from their text when empty. With ReplyTo the first
+// part replies to that post instead of starting a new thread, and Account
+// names the configured account to publish as
+type CreateThreadRequest struct {
+	Parts   []ThreadPartPayload `json:"parts" binding:"required,min=1,max=50,dive"`
+	Langs   []string            `json:"langs" binding:"max=3,dive,bcp47_language_tag"`
+	ReplyTo string              `json:"reply_to"`
+	Account string              `json:"account"`
+}
+
+// ThreadPartPayload is one post of an explicit thread. URL attaches a link
+// card to this post rather than adding a separate reply
+type ThreadPartPayload struct {
+	Text   string         `json:"text" binding:"required"`
+	Images []ImagePayload `json:"images" binding:"max=4,dive"`
+	Video  *VideoPayload  `json:"video"`
+	URL    string         `json:"url" binding:"omitempty,url"`
+	Quote  string         `json:"quote"`
+	Labels []string       `json:"labels" binding:"dive,oneof=sexual nudity porn graphic-media"`
+}
+
+// EditPostRequest replaces the text of a published post. Its embed is
+// replaced when images, video, url or quote is set, removed when
+// remove_embed is true and kept otherwise
+type EditPostRequest struct {
+	Text        string         `json:"text" binding:"required"`
+	Images      []ImagePayload `json:"images" binding:"max=4,dive"`
+	Video       *VideoPayload  `json:"video"`
+	URL         string         `json:"url" binding:"omitempty,url"`
+	Quote       string         `json:"quote"`
+	RemoveEmbed bool           `json:"remove_embed"`
+	Account     string         `json:"account"`
+}
+
+// ThreadPart is one post of an explicit thread with its media resolved
+type ThreadPart struct {
+	Text   string        `json:"text"`
+	Images []ImageUpload `json:"images,omitempty"`
+	Video  *VideoUpload  `json:"video,omitempty"`
+	URL    string        `json:"url,omitempty"`
+	Quote  string        `json:"quote,omitempty"`
+	Labels []string      `json:"labels,omitempty"`
+}
+
+// Scheduled post statuses
+const (
+	ScheduledStatusPending    = "pending"
+	ScheduledStatusPublishing = "publishing"
+	ScheduledStatusPublished  = "published"
+	ScheduledStatusFailed     = "failed"
+)
+
+// ScheduledPost describes a queued post without its media
+type ScheduledPost struct {
+	ID        string              `json:"id"`
+	Status    string              `json:"status"`
+	PublishAt time.Time           `json:"publish_at"`
+	Timezone  string              `json:"timezone"`
+	Text      string              `json:"text"`
+	CreatedAt time.Time           `json:"created_at"`
+	UpdatedAt time.Time           `json:"updated_at"`
+	Result    *CreatePostResponse `json:"result,omitempty"`
+	Error     string              `json:"error,omitempty"`
+}
+
+type RescheduleRequest struct {
+	PublishAt string `json:"publish_at" binding:"required"`
+	Timezone  string `json:"timezone" binding:"omitempty,timezone"`
+}
+
+// PreviewResponse lists the records a create request would write, in the
+// order they would be published. Every post after the first replies to the
+// one before it
+type PreviewResponse struct {
+	Posts []PreviewPost `json:"posts"`
+}
+
+// PreviewPost is a record that would be written. Media is not uploaded for a
+// preview, so the blob references of its embed are empty
+type PreviewPost struct {
+	Record    PostRecord     `json:"record"`
+	Graphemes int            `json:"graphemes"`
+	Facets    []PreviewFacet `json:"facets,omitempty"`
+	LinkCard  *LinkCard      `json:"link_card,omitempty"`
+}
+
+// PreviewFacet is a facet of a previewed post with the text it covers. Value
+// is the tag, link URI or mentioned DID
+type PreviewFacet struct {
+	Type      string `json:"type"`
+	Text      string `json:"text"`
+	ByteStart int    `json:"byte_start"`
+	ByteEnd   int    `json:"byte_end"`
+	Value     string `json:"value"`
+}
+
+// LinkCard is the Open Graph metadata a link card would be built from. Image
+// is the URL the thumbnail would be downloaded from
+type LinkCard struct {
+	URI         string `json:"uri"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Image       string `json:"image,omitempty"`
+}
+
+// GatesResponse describes the gates of a post after they were updated
+type GatesResponse struct {
+	URI           string     `json:"uri"`
+	ReplyGate     *ReplyGate `json:"reply_gate"`
+	DisableQuotes bool       `json:"disable_quotes"`
+}
+
+// Post visibility as reported by PostStatusResponse
+const (
+	PostStatusVisible  = "visible"
+	PostStatusNotFound = "not_found"
+	PostStatusBlocked  = "blocked"
+)
+
+// PostStatusResponse is the current state of a published post as the AppView
+// sees it. Only URI, Visible and Status are set when the post is deleted or
+// blocked
+type PostStatusResponse struct {
+	URI     string `json:"uri"`
+	CID     string `json:"cid,omitempty"`
+	Visible bool   `json:"visible"`
+	Status  string `json:"status"`
+
+	Author    string `json:"author,omitempty"`
+	Text      string `json:"text,omitempty"`
+	CreatedAt string `json:"created_at,omitempty"`
+	IndexedAt string `json:"indexed_at,omitempty"`
+
+	ReplyCount  int `json:"reply_count"`
+	RepostCount int `json:"repost_count"`
+	LikeCount   int `json:"like_count"`
+	QuoteCount  int `json:"quote_count"`
+
+	// Labels are the values of the labels on the post, self-labels included
+	Labels []string `json:"labels,omitempty"`
+
+	// Like and Repost are the account's own like and repost records, if any
+	Like   string `json:"like,omitempty"`
+	Repost string `json:"repost,omitempty"`
+}
+
+// AccountStatus describes a configured account and its session
+type AccountStatus struct {
+	Name   string `json:"name"`
+	Handle string `json:"handle"`
+	DID    string `json:"did,omitempty"`
+
+	// PDS is unset until discovered for accounts configuring none
+	PDS           string `json:"pds,omitempty"`
+	Default       bool   `json:"default"`
+	Authenticated bool   `json:"authenticated"`
+
+	// QueueDepth is the number of threads publishing or waiting to
+	QueueDepth int `json:"queue_depth"`
+
+	// Error is why the last login failed, if it did
+	Error string `json:"error,omitempty"`
+}
+
+// InteractionResponse is a like or repost of a post. URI is the record that
+// undoes it when deleted, and Existing is set when the post had already been
+// liked or reposted so no new record was written
+type InteractionResponse struct {
+	URI      string  `json:"uri"`
+	CID      string  `json:"cid,omitempty"`
+	Subject  PostRef `json:"subject"`
+	Existing bool    `json:"existing,omitempty"`
+}
+
+// DeletePostResponse lists the records that were deleted and those that
+// could not be
+type DeletePostResponse struct {
+	Deleted []string        `json:"deleted"`
+	Failed  []DeleteFailure `json:"failed,omitempty"`
+}
+
+type DeleteFailure struct {
+	URI   string `json:"uri"`
+	Error string `json:"error"`
 }
 
 type CreatePostResponse struct {
 	Posts []CreateRecordResponse `json:"posts"`
 	Error string                 `json:"error,omitempty"`
+
+	// Warnings reports problems that did not stop the posts from being
+	// published
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Queue reports how long the posts waited for earlier threads of the
+	// account to finish publishing
+	Queue *QueueInfo `json:"queue,omitempty"`
+}
+
+// RateLimitStatus is the rate limit budget a host last reported in its
+// ratelimit-* headers. Requests wait or are rejected while Remaining is 0
+// until Reset
+type RateLimitStatus struct {
+	Host      string    `json:"host"`
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+	Policy    string    `json:"policy,omitempty"`
+}
+
+// AccountRateLimits are the rate limit budgets of an account's hosts
+type AccountRateLimits struct {
+	Account string            `json:"account"`
+	Limits  []RateLimitStatus `json:"limits"`
+}
+
+// QueueInfo describes the wait in an account's publishing queue. Depth is
+// the number of threads ahead when the request was queued
+type QueueInfo struct {
+	Depth  int   `json:"depth"`
+	WaitMS int64 `json:"wait_ms"`
 }
 
 // Error types
@@ -0,0 +1,158 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/think-root/bluesky-connector/internal/logger"
+	"github.com/think-root/bluesky-connector/internal/models"
+)
+
+var ErrPublishAtInPast = errors.New("publish time is in the past")
+
+// Publisher publishes a post immediately
+type Publisher interface {
+	PostWithMedia(content *models.PostContent) (*models.CreatePostResponse, error)
+}
+
+// Scheduler publishes queued posts once they are due
+type Scheduler struct {
+	store     *Store
+	publisher Publisher
+	interval  time.Duration
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func New(store *Store, publisher Publisher, interval time.Duration) *Scheduler {
+	return &Scheduler{
+		store:     store,
+		publisher: publisher,
+		interval:  interval,
+		stop:      make(chan struct{}),
+	}
+}
+
+// Store returns the queue the scheduler publishes from
+func (s *Scheduler) Store() *Store {
+	return s.store
+}
+
+// Schedule queues content for publishing at publishAt, which must not be in
+// the past
+func (s *Scheduler) Schedule(content models.PostContent, publishAt time.Time, timezone string) (*models.ScheduledPost, error) {
+	if publishAt.Before(time.Now()) {
+		return nil, ErrPublishAtInPast
+	}
+	return s.store.Add(content, publishAt, timezone)
+}
+
+// Reschedule moves a pending post to a new publish time, which must not be in
+// the past
+func (s *Scheduler) Reschedule(id string, publishAt time.Time, timezone string) (*models.ScheduledPost, error) {
+	if publishAt.Before(time.Now()) {
+		return nil, ErrPublishAtInPast
+	}
+	return s.store.Reschedule(id, publishAt, timezone)
+}
+
+// Start runs the publishing loop in the background until Stop is called
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		logger.Infof("Scheduler started, checking for due posts every %v", s.interval)
+		s.publishDue()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.publishDue()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop waits for the post being published, if any, and stops the loop
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+	logger.Info("Scheduler stopped")
+}
+
+func (s *Scheduler) publishDue() {
+	for {
+		select {
+		case <-s.stop:
+			// Anything still due stays pending for the next start
+			return
+		default:
+		}
+
+		e, err := s.store.claimNext(time.Now())
+		if err != nil {
+			logger.Errorf("Failed to claim due scheduled post: %v", err)
+			return
+		}
+		if e == nil {
+			return
+		}
+
+		logger.Infof("Publishing scheduled post %s (due %s)", e.ID, e.PublishAt.Format(time.RFC3339))
+
+		result, publishErr := s.publisher.PostWithMedia(&e.Content)
+		if publishErr != nil {
+			logger.Errorf("Failed to publish scheduled post %s: %v", e.ID, publishErr)
+		} else {
+			logger.Infof("Published scheduled post %s with %d posts", e.ID, len(result.Posts))
+		}
+
+		if err := s.store.complete(e.ID, result, publishErr); err != nil {
+			logger.Errorf("Failed to record outcome of scheduled post %s: %v", e.ID, err)
+		}
+	}
+}
+
+var publishAtLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// ParsePublishAt parses an RFC 3339 timestamp, or a local date and time that
+// is read in the given IANA timezone (UTC when empty)
+func ParsePublishAt(value, timezone string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	location := time.UTC
+	if timezone != "" {
+		var err error
+		location, err = time.LoadLocation(timezone)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unknown timezone %q", timezone)
+		}
+	}
+
+	for _, layout := range publishAtLayouts {
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q, expected RFC 3339 or YYYY-MM-DD HH:MM[:SS]", value)
+}
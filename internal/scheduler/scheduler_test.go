@@ -0,0 +1,231 @@
+package scheduler
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/think-root/bluesky-connector/internal/logger"
+	"github.com/think-root/bluesky-connector/internal/models"
+)
+
+func init() {
+	logger.Init("error")
+}
+
+type fakePublisher struct {
+	mu        sync.Mutex
+	published []string
+	err       error
+	onPublish func()
+}
+
+func (p *fakePublisher) PostWithMedia(content *models.PostContent) (*models.CreatePostResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.onPublish != nil {
+		p.onPublish()
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	p.published = append(p.published, content.Text)
+	return &models.CreatePostResponse{
+		Posts: []models.CreateRecordResponse{{URI: "at://did:plc:test/app.bsky.feed.post/1", CID: "cid"}},
+	}, nil
+}
+
+func TestParsePublishAt(t *testing.T) {
+	kyiv, err := time.LoadLocation("Europe/Kyiv")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		value    string
+		timezone string
+		expected time.Time
+		wantErr  bool
+	}{
+		{
+			name:     "RFC 3339 ignores timezone",
+			value:    "2030-05-01T10:00:00+02:00",
+			timezone: "America/New_York",
+			expected: time.Date(2030, 5, 1, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "local time defaults to UTC",
+			value:    "2030-05-01 10:00",
+			expected: time.Date(2030, 5, 1, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "local time in timezone",
+			value:    "2030-05-01T10:00:30",
+			timezone: "Europe/Kyiv",
+			expected: time.Date(2030, 5, 1, 10, 0, 30, 0, kyiv),
+		},
+		{
+			name:     "unknown timezone",
+			value:    "2030-05-01 10:00",
+			timezone: "Nowhere/City",
+			wantErr:  true,
+		},
+		{
+			name:    "malformed",
+			value:   "next tuesday",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParsePublishAt(tt.value, tt.timezone)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.expected.Equal(result), "expected %v, got %v", tt.expected, result)
+		})
+	}
+}
+
+func TestStore_PersistsAcrossRestarts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue", "scheduled.json")
+	publishAt := time.Now().Add(time.Hour)
+
+	store, err := NewStore(path)
+	require.NoError(t, err)
+
+	first, err := store.Add(models.PostContent{Text: "first"}, publishAt, "Europe/Kyiv")
+	require.NoError(t, err)
+	second, err := store.Add(models.PostContent{Text: "second"}, publishAt.Add(-time.Minute), "")
+	require.NoError(t, err)
+
+	_, err = store.Cancel(second.ID)
+	require.NoError(t, err)
+
+	reloaded, err := NewStore(path)
+	require.NoError(t, err)
+
+	posts := reloaded.List("")
+	require.Len(t, posts, 1)
+	assert.Equal(t, first.ID, posts[0].ID)
+	assert.Equal(t, "first", posts[0].Text)
+	assert.Equal(t, "Europe/Kyiv", posts[0].Timezone)
+	assert.Equal(t, models.ScheduledStatusPending, posts[0].Status)
+	assert.True(t, publishAt.Equal(posts[0].PublishAt))
+}
+
+func TestStore_InterruptedPostsFail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scheduled.json")
+
+	store, err := NewStore(path)
+	require.NoError(t, err)
+
+	post, err := store.Add(models.PostContent{Text: "hello"}, time.Now().Add(-time.Second), "")
+	require.NoError(t, err)
+
+	claimed, err := store.claimNext(time.Now())
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+
+	// Simulate a restart while the post was being published
+	reloaded, err := NewStore(path)
+	require.NoError(t, err)
+
+	reloadedPost, err := reloaded.Get(post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ScheduledStatusFailed, reloadedPost.Status)
+	assert.NotEmpty(t, reloadedPost.Error)
+
+	_, err = reloaded.Reschedule(post.ID, time.Now().Add(time.Hour), "")
+	assert.ErrorIs(t, err, ErrNotPending)
+	_, err = reloaded.Cancel("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestScheduler_PublishesDuePosts(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "scheduled.json"))
+	require.NoError(t, err)
+
+	due, err := store.Add(models.PostContent{Text: "due"}, time.Now().Add(-time.Minute), "")
+	require.NoError(t, err)
+	later, err := store.Add(models.PostContent{Text: "later"}, time.Now().Add(time.Hour), "")
+	require.NoError(t, err)
+
+	publisher := &fakePublisher{}
+	New(store, publisher, time.Minute).publishDue()
+
+	assert.Equal(t, []string{"due"}, publisher.published)
+
+	published, err := store.Get(due.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ScheduledStatusPublished, published.Status)
+	require.NotNil(t, published.Result)
+	assert.Len(t, published.Result.Posts, 1)
+
+	pending, err := store.Get(later.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ScheduledStatusPending, pending.Status)
+}
+
+func TestScheduler_RecordsFailures(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "scheduled.json"))
+	require.NoError(t, err)
+
+	post, err := store.Add(models.PostContent{Text: "due"}, time.Now().Add(-time.Minute), "")
+	require.NoError(t, err)
+
+	New(store, &fakePublisher{err: errors.New("boom")}, time.Minute).publishDue()
+
+	failed, err := store.Get(post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ScheduledStatusFailed, failed.Status)
+	assert.Equal(t, "boom", failed.Error)
+}
+
+func TestScheduler_StopLeavesUnattemptedPostsPending(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scheduled.json")
+	store, err := NewStore(path)
+	require.NoError(t, err)
+
+	first, err := store.Add(models.PostContent{Text: "first"}, time.Now().Add(-2*time.Minute), "")
+	require.NoError(t, err)
+	second, err := store.Add(models.PostContent{Text: "second"}, time.Now().Add(-time.Minute), "")
+	require.NoError(t, err)
+
+	publisher := &fakePublisher{}
+	s := New(store, publisher, time.Minute)
+	// Stop arrives while the first post is being published
+	publisher.onPublish = func() { close(s.stop) }
+	s.publishDue()
+
+	assert.Equal(t, []string{"first"}, publisher.published)
+
+	reloaded, err := NewStore(path)
+	require.NoError(t, err)
+
+	published, err := reloaded.Get(first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ScheduledStatusPublished, published.Status)
+
+	pending, err := reloaded.Get(second.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ScheduledStatusPending, pending.Status)
+	assert.Empty(t, pending.Error)
+}
+
+func TestScheduler_RejectsPastTimes(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "scheduled.json"))
+	require.NoError(t, err)
+
+	s := New(store, &fakePublisher{}, time.Minute)
+	_, err = s.Schedule(models.PostContent{Text: "late"}, time.Now().Add(-time.Minute), "")
+	assert.ErrorIs(t, err, ErrPublishAtInPast)
+	assert.Empty(t, store.List(""))
+}
@@ -0,0 +1,296 @@
+package scheduler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/think-root/bluesky-connector/internal/fsutil"
+	"github.com/think-root/bluesky-connector/internal/models"
+)
+
+var (
+	ErrNotFound   = errors.New("scheduled post not found")
+	ErrNotPending = errors.New("scheduled post is no longer pending")
+)
+
+// completedRetention is how long published and failed posts stay listed
+const completedRetention = 7 * 24 * time.Hour
+
+// entry is a scheduled post as persisted, including the content to publish
+type entry struct {
+	models.ScheduledPost
+	Content models.PostContent `json:"content"`
+}
+
+// Store keeps the scheduled posts in memory and mirrors every change to a
+// JSON file so the queue survives restarts
+type Store struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// NewStore loads the queue from path, creating an empty one if the file does
+// not exist yet. Posts that were being published when the process stopped
+// are marked as failed rather than retried, since they may have been
+// partially published
+func NewStore(path string) (*Store, error) {
+	s := &Store{
+		path:    path,
+		entries: make(map[string]*entry),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queue file: %w", err)
+	}
+
+	var entries []*entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse queue file: %w", err)
+	}
+
+	interrupted := false
+	for _, e := range entries {
+		if e.Status == models.ScheduledStatusPublishing {
+			e.Status = models.ScheduledStatusFailed
+			e.Error = "publishing was interrupted by a restart"
+			e.UpdatedAt = time.Now().UTC()
+			interrupted = true
+		}
+		s.entries[e.ID] = e
+	}
+
+	if interrupted {
+		if err := s.save(); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Add queues content for publishing at publishAt
+func (s *Store) Add(content models.PostContent, publishAt time.Time, timezone string) (*models.ScheduledPost, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	e := &entry{
+		ScheduledPost: models.ScheduledPost{
+			ID:        id,
+			Status:    models.ScheduledStatusPending,
+			PublishAt: publishAt.UTC(),
+			Timezone:  timezone,
+			Text:      content.Text,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		Content: content,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[id] = e
+	if err := s.save(); err != nil {
+		delete(s.entries, id)
+		return nil, err
+	}
+
+	post := e.ScheduledPost
+	return &post, nil
+}
+
+// List returns the scheduled posts ordered by publish time, optionally
+// filtered by status
+func (s *Store) List(status string) []models.ScheduledPost {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	posts := make([]models.ScheduledPost, 0, len(s.entries))
+	for _, e := range s.entries {
+		if status == "" || e.Status == status {
+			posts = append(posts, e.ScheduledPost)
+		}
+	}
+
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].PublishAt.Before(posts[j].PublishAt)
+	})
+
+	return posts
+}
+
+// Get returns a single scheduled post
+func (s *Store) Get(id string) (*models.ScheduledPost, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	post := e.ScheduledPost
+	return &post, nil
+}
+
+// Reschedule moves a pending post to a new publish time
+func (s *Store) Reschedule(id string, publishAt time.Time, timezone string) (*models.ScheduledPost, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if e.Status != models.ScheduledStatusPending {
+		return nil, ErrNotPending
+	}
+
+	previous := e.ScheduledPost
+	e.PublishAt = publishAt.UTC()
+	e.Timezone = timezone
+	e.UpdatedAt = time.Now().UTC()
+
+	if err := s.save(); err != nil {
+		e.ScheduledPost = previous
+		return nil, err
+	}
+
+	post := e.ScheduledPost
+	return &post, nil
+}
+
+// Cancel removes a pending post from the queue
+func (s *Store) Cancel(id string) (*models.ScheduledPost, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if e.Status != models.ScheduledStatusPending {
+		return nil, ErrNotPending
+	}
+
+	delete(s.entries, id)
+	if err := s.save(); err != nil {
+		s.entries[id] = e
+		return nil, err
+	}
+
+	post := e.ScheduledPost
+	return &post, nil
+}
+
+// claimNext marks the oldest pending post due at now as publishing and
+// returns it with its content, or nil when nothing is due. Posts are claimed
+// one at a time so a shutdown never leaves more than the one in flight behind
+func (s *Store) claimNext(now time.Time) (*entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var next *entry
+	for _, e := range s.entries {
+		if e.Status != models.ScheduledStatusPending || e.PublishAt.After(now) {
+			continue
+		}
+		if next == nil || e.PublishAt.Before(next.PublishAt) {
+			next = e
+		}
+	}
+
+	if next == nil {
+		return nil, nil
+	}
+
+	previous := next.ScheduledPost
+	next.Status = models.ScheduledStatusPublishing
+	next.UpdatedAt = now.UTC()
+
+	if err := s.save(); err != nil {
+		next.ScheduledPost = previous
+		return nil, err
+	}
+
+	copied := *next
+	return &copied, nil
+}
+
+// complete records the outcome of publishing a post
+func (s *Store) complete(id string, result *models.CreatePostResponse, publishErr error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	if publishErr != nil {
+		// Result holds the posts published before the failure, if any
+		e.Status = models.ScheduledStatusFailed
+		e.Error = publishErr.Error()
+		e.Result = result
+	} else {
+		e.Status = models.ScheduledStatusPublished
+		e.Result = result
+		e.Error = ""
+	}
+	e.UpdatedAt = time.Now().UTC()
+
+	// The media is no longer needed once the post is done with
+	e.Content.Images = nil
+	e.Content.Video = nil
+
+	return s.save()
+}
+
+// save writes the queue to disk, dropping completed posts past their
+// retention. Callers must hold s.mu
+func (s *Store) save() error {
+	cutoff := time.Now().Add(-completedRetention)
+	entries := make([]*entry, 0, len(s.entries))
+	for id, e := range s.entries {
+		done := e.Status == models.ScheduledStatusPublished || e.Status == models.ScheduledStatusFailed
+		if done && e.UpdatedAt.Before(cutoff) {
+			delete(s.entries, id)
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("failed to encode queue: %w", err)
+	}
+
+	if err := fsutil.WriteFileAtomic(s.path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save queue: %w", err)
+	}
+
+	return nil
+}
+
+func newID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,119 @@
+package sessionstore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/think-root/bluesky-connector/internal/fsutil"
+)
+
+// Session is a Bluesky session saved so a restart can resume it instead of
+// logging in again
+type Session struct {
+	// Identifier is the handle or DID the session was created for
+	Identifier string    `json:"identifier"`
+	PDS        string    `json:"pds"`
+	DID        string    `json:"did"`
+	AccessJWT  string    `json:"access_jwt"`
+	RefreshJWT string    `json:"refresh_jwt"`
+	SavedAt    time.Time `json:"saved_at"`
+}
+
+// Store keeps one account's session in a file encrypted with AES-256-GCM
+// under a key derived from secret
+type Store struct {
+	path string
+	aead cipher.AEAD
+}
+
+func New(path, secret string) *Store {
+	key := sha256.Sum256([]byte(secret))
+
+	// A 32-byte key always makes a valid AES-256 block and GCM mode
+	block, _ := aes.NewCipher(key[:])
+	aead, _ := cipher.NewGCM(block)
+
+	return &Store{path: path, aead: aead}
+}
+
+// CheckWritable reports an error when the session cannot be saved because its
+// directory cannot be created or written to
+func (s *Store) CheckWritable() error {
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	probe, err := os.CreateTemp(dir, ".probe-*")
+	if err != nil {
+		return fmt.Errorf("session directory is not writable: %w", err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	return nil
+}
+
+// Load returns the saved session, or nil when none was saved. A file that
+// cannot be decrypted, e.g. because the secret changed, is an error
+func (s *Store) Load() (*Session, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+
+	nonceSize := s.aead.NonceSize()
+	if len(data) < nonceSize {
+		return nil, fmt.Errorf("failed to decrypt session: file is truncated")
+	}
+
+	plaintext, err := s.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt session: %w", err)
+	}
+
+	var session Session
+	if err := json.Unmarshal(plaintext, &session); err != nil {
+		return nil, fmt.Errorf("failed to parse session: %w", err)
+	}
+
+	return &session, nil
+}
+
+// Save encrypts session and replaces the saved one, readable by the owner only
+func (s *Store) Save(session *Session) error {
+	plaintext, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	if err := fsutil.WriteFileAtomic(s.path, s.aead.Seal(nonce, nonce, plaintext, nil), 0o600); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	return nil
+}
+
+// Remove deletes the saved session, if any
+func (s *Store) Remove() error {
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove session: %w", err)
+	}
+	return nil
+}
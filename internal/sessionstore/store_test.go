@@ -0,0 +1,71 @@
+package sessionstore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions", "default.session")
+	store := New(path, "secret")
+
+	session, err := store.Load()
+	require.NoError(t, err)
+	assert.Nil(t, session)
+
+	saved := &Session{
+		Identifier: "alice.bsky.social",
+		PDS:        "https://bsky.social",
+		DID:        "did:plc:abc",
+		AccessJWT:  "access-token",
+		RefreshJWT: "refresh-token",
+		SavedAt:    time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	require.NoError(t, store.Save(saved))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// The tokens are not stored in the clear
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "refresh-token")
+
+	session, err = New(path, "secret").Load()
+	require.NoError(t, err)
+	assert.Equal(t, saved, session)
+
+	require.NoError(t, store.Remove())
+	require.NoError(t, store.Remove())
+	session, err = store.Load()
+	require.NoError(t, err)
+	assert.Nil(t, session)
+}
+
+func TestStore_WrongSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "default.session")
+	require.NoError(t, New(path, "secret").Save(&Session{AccessJWT: "a"}))
+
+	_, err := New(path, "other").Load()
+	assert.ErrorContains(t, err, "failed to decrypt session")
+
+	require.NoError(t, os.WriteFile(path, []byte("short"), 0o600))
+	_, err = New(path, "secret").Load()
+	assert.ErrorContains(t, err, "file is truncated")
+}
+
+func TestStore_CheckWritable(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, New(filepath.Join(dir, "sessions", "default.session"), "secret").CheckWritable())
+
+	// A file where the directory should be cannot hold sessions
+	blocker := filepath.Join(dir, "blocker")
+	require.NoError(t, os.WriteFile(blocker, nil, 0o600))
+	assert.ErrorContains(t, New(filepath.Join(blocker, "default.session"), "secret").CheckWritable(), "failed to create session directory")
+}
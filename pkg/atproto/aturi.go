@@ -0,0 +1,76 @@
+package atproto
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ATURI identifies a record by repository (DID or handle), collection and
+// record key, e.g. at://did:plc:abc/app.bsky.feed.post/3k2a
+type ATURI struct {
+	Repo       string
+	Collection string
+	RKey       string
+}
+
+func (u ATURI) String() string {
+	return fmt.Sprintf("at://%s/%s/%s", u.Repo, u.Collection, u.RKey)
+}
+
+// HasDID reports whether the repository is already a DID rather than a handle
+func (u ATURI) HasDID() bool {
+	return strings.HasPrefix(u.Repo, "did:")
+}
+
+// ParseATURI parses a record AT-URI
+func ParseATURI(s string) (*ATURI, error) {
+	rest, ok := strings.CutPrefix(s, "at://")
+	if !ok {
+		return nil, fmt.Errorf("invalid AT-URI %q: missing at:// scheme", s)
+	}
+
+	parts := strings.Split(rest, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return nil, fmt.Errorf("invalid AT-URI %q: expected at://<repo>/<collection>/<rkey>", s)
+	}
+
+	return &ATURI{
+		Repo:       parts[0],
+		Collection: parts[1],
+		RKey:       parts[2],
+	}, nil
+}
+
+// ParsePostURI accepts either a post AT-URI or a bsky.app post URL such as
+// https://bsky.app/profile/alice.bsky.social/post/3k2a
+func ParsePostURI(s string) (*ATURI, error) {
+	s = strings.TrimSpace(s)
+
+	if strings.HasPrefix(s, "at://") {
+		uri, err := ParseATURI(s)
+		if err != nil {
+			return nil, err
+		}
+		if uri.Collection != PostCollection {
+			return nil, fmt.Errorf("%q is not a post: collection is %s", s, uri.Collection)
+		}
+		return uri, nil
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host != "bsky.app" {
+		return nil, fmt.Errorf("%q is neither an AT-URI nor a bsky.app post URL", s)
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 4 || parts[0] != "profile" || parts[2] != "post" || parts[1] == "" || parts[3] == "" {
+		return nil, fmt.Errorf("%q is not a bsky.app post URL", s)
+	}
+
+	return &ATURI{
+		Repo:       parts[1],
+		Collection: PostCollection,
+		RKey:       parts[3],
+	}, nil
+}
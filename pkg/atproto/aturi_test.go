@@ -0,0 +1,75 @@
+package atproto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePostURI(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected *ATURI
+		wantErr  bool
+	}{
+		{
+			name:     "AT-URI with DID",
+			input:    "at://did:plc:abc123/app.bsky.feed.post/3k2a4b",
+			expected: &ATURI{Repo: "did:plc:abc123", Collection: PostCollection, RKey: "3k2a4b"},
+		},
+		{
+			name:     "AT-URI with handle",
+			input:    "at://alice.bsky.social/app.bsky.feed.post/3k2a4b",
+			expected: &ATURI{Repo: "alice.bsky.social", Collection: PostCollection, RKey: "3k2a4b"},
+		},
+		{
+			name:     "bsky.app URL with handle",
+			input:    "https://bsky.app/profile/alice.bsky.social/post/3k2a4b",
+			expected: &ATURI{Repo: "alice.bsky.social", Collection: PostCollection, RKey: "3k2a4b"},
+		},
+		{
+			name:     "bsky.app URL with DID and trailing slash",
+			input:    "https://bsky.app/profile/did:plc:abc123/post/3k2a4b/",
+			expected: &ATURI{Repo: "did:plc:abc123", Collection: PostCollection, RKey: "3k2a4b"},
+		},
+		{
+			name:    "AT-URI of another collection",
+			input:   "at://did:plc:abc123/app.bsky.feed.like/3k2a4b",
+			wantErr: true,
+		},
+		{
+			name:    "bsky.app profile URL",
+			input:   "https://bsky.app/profile/alice.bsky.social",
+			wantErr: true,
+		},
+		{
+			name:    "Other host",
+			input:   "https://example.com/profile/alice.bsky.social/post/3k2a4b",
+			wantErr: true,
+		},
+		{
+			name:    "Incomplete AT-URI",
+			input:   "at://did:plc:abc123",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uri, err := ParsePostURI(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, uri)
+		})
+	}
+}
+
+func TestATURI_String(t *testing.T) {
+	uri := ATURI{Repo: "did:plc:abc123", Collection: PostCollection, RKey: "3k2a4b"}
+	assert.Equal(t, "at://did:plc:abc123/app.bsky.feed.post/3k2a4b", uri.String())
+	assert.True(t, uri.HasDID())
+}
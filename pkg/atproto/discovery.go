@@ -0,0 +1,159 @@
+package atproto
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/think-root/bluesky-connector/internal/models"
+)
+
+const (
+	DefaultPLCDirectoryURL = "https://plc.directory"
+
+	// pdsServiceID and pdsServiceType identify the PDS entry among the
+	// services of a DID document
+	pdsServiceID   = "#atproto_pds"
+	pdsServiceType = "AtprotoPersonalDataServer"
+)
+
+// PDSDiscovery finds the PDS hosting an account without asking any PDS: the
+// handle is resolved to a DID through DNS or its well-known file, and the
+// PDS is read from the DID document, fetched from the PLC directory for
+// did:plc and from the domain itself for did:web
+type PDSDiscovery struct {
+	plcDirectoryURL string
+	httpClient      *http.Client
+
+	// lookupTXT and webScheme are swapped out in tests
+	lookupTXT func(name string) ([]string, error)
+	webScheme string
+}
+
+func NewPDSDiscovery(plcDirectoryURL string) *PDSDiscovery {
+	if plcDirectoryURL == "" {
+		plcDirectoryURL = DefaultPLCDirectoryURL
+	}
+
+	return &PDSDiscovery{
+		plcDirectoryURL: strings.TrimRight(plcDirectoryURL, "/"),
+		httpClient: &http.Client{
+			Timeout: 15 * time.Second,
+		},
+		lookupTXT: net.LookupTXT,
+		webScheme: "https",
+	}
+}
+
+// DiscoverPDS returns the PDS URL of the account with the given handle or DID
+func (d *PDSDiscovery) DiscoverPDS(identifier string) (string, error) {
+	did := strings.TrimSpace(identifier)
+	if !strings.HasPrefix(did, "did:") {
+		var err error
+		did, err = d.ResolveHandle(did)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	doc, err := d.ResolveDID(did)
+	if err != nil {
+		return "", err
+	}
+
+	for _, service := range doc.Service {
+		if strings.HasSuffix(service.ID, pdsServiceID) && service.Type == pdsServiceType {
+			endpoint, err := url.Parse(service.ServiceEndpoint)
+			if err != nil || (endpoint.Scheme != "https" && endpoint.Scheme != "http") || endpoint.Host == "" {
+				return "", fmt.Errorf("DID document of %s has an invalid PDS endpoint %q", did, service.ServiceEndpoint)
+			}
+			return strings.TrimRight(service.ServiceEndpoint, "/"), nil
+		}
+	}
+
+	return "", fmt.Errorf("DID document of %s lists no PDS", did)
+}
+
+// ResolveHandle returns the DID of a handle from the _atproto DNS TXT record
+// of its domain, falling back to https://<handle>/.well-known/atproto-did
+func (d *PDSDiscovery) ResolveHandle(handle string) (string, error) {
+	handle = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(handle), "@"))
+
+	if records, err := d.lookupTXT("_atproto." + handle); err == nil {
+		for _, record := range records {
+			if did, ok := strings.CutPrefix(record, "did="); ok && strings.HasPrefix(did, "did:") {
+				return did, nil
+			}
+		}
+	}
+
+	body, err := d.get(d.webScheme + "://" + handle + "/.well-known/atproto-did")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve handle %s: %w", handle, err)
+	}
+
+	did := strings.TrimSpace(string(body))
+	if !strings.HasPrefix(did, "did:") {
+		return "", fmt.Errorf("failed to resolve handle %s: well-known file holds no DID", handle)
+	}
+	return did, nil
+}
+
+// ResolveDID fetches the DID document of a did:plc or did:web identifier
+func (d *PDSDiscovery) ResolveDID(did string) (*models.DIDDocument, error) {
+	var docURL string
+	switch {
+	case strings.HasPrefix(did, "did:plc:"):
+		docURL = d.plcDirectoryURL + "/" + did
+	case strings.HasPrefix(did, "did:web:"):
+		// did:web:example.com%3A8080:user maps to
+		// https://example.com:8080/user/did.json
+		parts := strings.Split(strings.TrimPrefix(did, "did:web:"), ":")
+		host, err := url.PathUnescape(parts[0])
+		if err != nil || host == "" {
+			return nil, fmt.Errorf("invalid did:web identifier %s", did)
+		}
+		if len(parts) == 1 {
+			docURL = d.webScheme + "://" + host + "/.well-known/did.json"
+		} else {
+			docURL = d.webScheme + "://" + host + "/" + strings.Join(parts[1:], "/") + "/did.json"
+		}
+	default:
+		return nil, fmt.Errorf("unsupported DID method: %s", did)
+	}
+
+	body, err := d.get(docURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch DID document of %s: %w", did, err)
+	}
+
+	var doc models.DIDDocument
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode DID document of %s: %w", did, err)
+	}
+	if doc.ID != did {
+		return nil, fmt.Errorf("DID document of %s is for %s", did, doc.ID)
+	}
+
+	return &doc, nil
+}
+
+func (d *PDSDiscovery) get(rawURL string) ([]byte, error) {
+	resp, err := d.httpClient.Get(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP error: %d", resp.StatusCode)
+	}
+
+	// DID documents and well-known files are small
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
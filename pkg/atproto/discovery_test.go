@@ -0,0 +1,108 @@
+package atproto
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/think-root/bluesky-connector/internal/models"
+)
+
+func pdsDocument(did, endpoint string) models.DIDDocument {
+	return models.DIDDocument{
+		ID: did,
+		Service: []models.DIDService{
+			{ID: "#atproto_labeler", Type: "AtprotoLabeler", ServiceEndpoint: "https://labeler.example.com"},
+			{ID: did + "#atproto_pds", Type: "AtprotoPersonalDataServer", ServiceEndpoint: endpoint},
+		},
+	}
+}
+
+func TestPDSDiscovery_DNSAndPLC(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/did:plc:abc", r.URL.Path)
+		json.NewEncoder(w).Encode(pdsDocument("did:plc:abc", "https://pds.example.com/"))
+	}))
+	defer server.Close()
+
+	discovery := NewPDSDiscovery(server.URL + "/")
+	discovery.lookupTXT = func(name string) ([]string, error) {
+		assert.Equal(t, "_atproto.alice.example.com", name)
+		return []string{"v=spf1 -all", "did=did:plc:abc"}, nil
+	}
+
+	pdsURL, err := discovery.DiscoverPDS("@Alice.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "https://pds.example.com", pdsURL)
+}
+
+func TestPDSDiscovery_WellKnownAndDIDWeb(t *testing.T) {
+	var did string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/atproto-did":
+			w.Write([]byte(did + "\n"))
+		case "/.well-known/did.json":
+			json.NewEncoder(w).Encode(pdsDocument(did, "https://pds.example.com"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	// The port of a did:web host is percent-encoded
+	host := strings.TrimPrefix(server.URL, "http://")
+	did = "did:web:" + strings.ReplaceAll(host, ":", "%3A")
+
+	discovery := NewPDSDiscovery("")
+	discovery.webScheme = "http"
+	discovery.lookupTXT = func(string) ([]string, error) {
+		return nil, errors.New("no such host")
+	}
+
+	pdsURL, err := discovery.DiscoverPDS(host)
+	require.NoError(t, err)
+	assert.Equal(t, "https://pds.example.com", pdsURL)
+}
+
+func TestPDSDiscovery_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/did:plc:nopds":
+			json.NewEncoder(w).Encode(models.DIDDocument{ID: "did:plc:nopds"})
+		case "/did:plc:other":
+			json.NewEncoder(w).Encode(pdsDocument("did:plc:abc", "https://pds.example.com"))
+		case "/did:plc:badendpoint":
+			json.NewEncoder(w).Encode(pdsDocument("did:plc:badendpoint", "pds.example.com"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	discovery := NewPDSDiscovery(server.URL)
+
+	tests := []struct {
+		did     string
+		wantErr string
+	}{
+		{"did:plc:nopds", "lists no PDS"},
+		{"did:plc:other", "is for did:plc:abc"},
+		{"did:plc:badendpoint", "invalid PDS endpoint"},
+		{"did:plc:missing", "HTTP error: 404"},
+		{"did:key:z6Mk", "unsupported DID method"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.did, func(t *testing.T) {
+			_, err := discovery.DiscoverPDS(tt.did)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
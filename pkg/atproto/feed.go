@@ -0,0 +1,207 @@
+package atproto
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/think-root/bluesky-connector/internal/models"
+)
+
+const (
+	GetPostsEndpoint      = "/xrpc/app.bsky.feed.getPosts"
+	GetPostThreadEndpoint = "/xrpc/app.bsky.feed.getPostThread"
+
+	// MaxThreadDepth is the deepest getPostThread will return replies
+	MaxThreadDepth = 1000
+)
+
+// ErrPostNotFound is returned when the AppView has no view of a post, as for
+// deleted posts
+var ErrPostNotFound = errors.New("post not found")
+
+// FeedManager reads posts through the app.bsky.feed queries, which the PDS
+// proxies to the AppView
+type FeedManager struct {
+	baseURL          string
+	httpClient       *http.Client
+	sessionManager   *SessionManager
+	identityResolver *IdentityResolver
+}
+
+func NewFeedManager(baseURL string, sessionManager *SessionManager, identityResolver *IdentityResolver) *FeedManager {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	return &FeedManager{
+		baseURL:          baseURL,
+		httpClient:       sessionManager.httpClient,
+		sessionManager:   sessionManager,
+		identityResolver: identityResolver,
+	}
+}
+
+// GetPosts returns the views of the given post AT-URIs. Posts that are deleted
+// or not visible to the account are missing from the result
+func (fm *FeedManager) GetPosts(uris []string) ([]models.PostView, error) {
+	if !fm.sessionManager.IsAuthenticated() {
+		return nil, fmt.Errorf("not authenticated")
+	}
+
+	params := url.Values{}
+	for _, uri := range uris {
+		params.Add("uris", uri)
+	}
+
+	req, err := http.NewRequest("GET", fm.baseURL+GetPostsEndpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+fm.sessionManager.GetAccessToken())
+
+	resp, err := fm.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newXRPCError(resp)
+	}
+
+	var postsResp models.GetPostsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&postsResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return postsResp.Posts, nil
+}
+
+// GetPostThread returns a post with its replies down to depth levels, without
+// its parents
+func (fm *FeedManager) GetPostThread(uri string, depth int) (*models.ThreadViewPost, error) {
+	if !fm.sessionManager.IsAuthenticated() {
+		return nil, fmt.Errorf("not authenticated")
+	}
+
+	params := url.Values{}
+	params.Set("uri", uri)
+	params.Set("depth", strconv.Itoa(depth))
+	params.Set("parentHeight", "0")
+
+	req, err := http.NewRequest("GET", fm.baseURL+GetPostThreadEndpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+fm.sessionManager.GetAccessToken())
+
+	resp, err := fm.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newXRPCError(resp)
+	}
+
+	var threadResp models.GetPostThreadResponse
+	if err := json.NewDecoder(resp.Body).Decode(&threadResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &threadResp.Thread, nil
+}
+
+// ResolvePostURI turns a post AT-URI or bsky.app URL into an AT-URI whose
+// repository is a DID
+func (fm *FeedManager) ResolvePostURI(ref string) (*ATURI, error) {
+	uri, err := ParsePostURI(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if !uri.HasDID() {
+		did, err := fm.identityResolver.ResolveHandle(uri.Repo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve handle %s: %w", uri.Repo, err)
+		}
+		uri.Repo = did
+	}
+
+	return uri, nil
+}
+
+// GetPost looks up the view of a post AT-URI or bsky.app URL, resolving the
+// author's handle to a DID
+func (fm *FeedManager) GetPost(ref string) (*models.PostView, error) {
+	uri, err := fm.ResolvePostURI(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	posts, err := fm.GetPosts([]string{uri.String()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch post %s: %w", uri, err)
+	}
+	if len(posts) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrPostNotFound, uri)
+	}
+
+	return &posts[0], nil
+}
+
+// ResolvePostRef turns a post AT-URI or bsky.app URL into a strong reference,
+// resolving the author's handle to a DID and looking up the current CID
+func (fm *FeedManager) ResolvePostRef(ref string) (*models.PostRef, error) {
+	post, err := fm.GetPost(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PostRef{
+		URI: post.URI,
+		CID: post.CID,
+	}, nil
+}
+
+// ResolveReply looks up a post AT-URI or bsky.app URL and returns the reply
+// references of a new post answering it: the post itself as parent and the
+// root of its thread as root
+func (fm *FeedManager) ResolveReply(ref string) (*models.Reply, error) {
+	uri, err := fm.ResolvePostURI(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	thread, err := fm.GetPostThread(uri.String(), 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch post %s: %w", uri, err)
+	}
+	if thread.Post == nil {
+		return nil, fmt.Errorf("post %s is not available (%s)", uri, thread.Type)
+	}
+
+	parent := &models.PostRef{URI: thread.Post.URI, CID: thread.Post.CID}
+
+	var record struct {
+		Reply *models.Reply `json:"reply"`
+	}
+	if err := json.Unmarshal(thread.Post.Record, &record); err != nil {
+		return nil, fmt.Errorf("failed to decode post %s: %w", uri, err)
+	}
+
+	// A post that is not itself a reply is the root of its thread
+	root := parent
+	if record.Reply != nil && record.Reply.Root != nil {
+		root = record.Reply.Root
+	}
+
+	return &models.Reply{Root: root, Parent: parent}, nil
+}
@@ -0,0 +1,78 @@
+package atproto
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/think-root/bluesky-connector/internal/models"
+)
+
+func TestFeedManager_ResolveReply(t *testing.T) {
+	records := map[string]string{
+		"at://did:plc:me/app.bsky.feed.post/root":  `{"text": "Weekly digest"}`,
+		"at://did:plc:me/app.bsky.feed.post/reply": `{"text": "Part 2", "reply": {"root": {"uri": "at://did:plc:me/app.bsky.feed.post/root", "cid": "rootcid"}, "parent": {"uri": "at://did:plc:me/app.bsky.feed.post/root", "cid": "rootcid"}}}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, GetPostThreadEndpoint, r.URL.Path)
+		assert.Equal(t, "0", r.URL.Query().Get("depth"))
+
+		uri := r.URL.Query().Get("uri")
+		record, ok := records[uri]
+		if !ok {
+			json.NewEncoder(w).Encode(map[string]any{
+				"thread": map[string]any{"$type": "app.bsky.feed.defs#notFoundPost", "uri": uri, "notFound": true},
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"thread": map[string]any{
+				"$type": "app.bsky.feed.defs#threadViewPost",
+				"post": map[string]any{
+					"uri":    uri,
+					"cid":    path.Base(uri) + "cid",
+					"record": json.RawMessage(record),
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	sm := NewSessionManager(server.URL)
+	sm.accessToken = "token"
+	fm := NewFeedManager(server.URL, sm, nil)
+
+	rootRef := &models.PostRef{URI: "at://did:plc:me/app.bsky.feed.post/root", CID: "rootcid"}
+
+	reply, err := fm.ResolveReply("at://did:plc:me/app.bsky.feed.post/root")
+	require.NoError(t, err)
+	assert.Equal(t, &models.Reply{Root: rootRef, Parent: rootRef}, reply)
+
+	reply, err = fm.ResolveReply("https://bsky.app/profile/did:plc:me/post/reply")
+	require.NoError(t, err)
+	assert.Equal(t, rootRef, reply.Root)
+	assert.Equal(t, &models.PostRef{URI: "at://did:plc:me/app.bsky.feed.post/reply", CID: "replycid"}, reply.Parent)
+
+	_, err = fm.ResolveReply("at://did:plc:me/app.bsky.feed.post/gone")
+	assert.ErrorContains(t, err, "notFoundPost")
+}
+
+func TestFeedManager_GetPostNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, GetPostsEndpoint, r.URL.Path)
+		json.NewEncoder(w).Encode(map[string]any{"posts": []any{}})
+	}))
+	defer server.Close()
+
+	sm := NewSessionManager(server.URL)
+	sm.accessToken = "token"
+	fm := NewFeedManager(server.URL, sm, nil)
+
+	_, err := fm.GetPost("at://did:plc:me/app.bsky.feed.post/gone")
+	assert.ErrorIs(t, err, ErrPostNotFound)
+}
@@ -0,0 +1,133 @@
+package atproto
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/think-root/bluesky-connector/internal/logger"
+	"github.com/think-root/bluesky-connector/internal/models"
+)
+
+const (
+	ThreadgateCollection = "app.bsky.feed.threadgate"
+	PostgateCollection   = "app.bsky.feed.postgate"
+	ListCollection       = "app.bsky.graph.list"
+
+	// MaxThreadgateRules is how many rules a threadgate can hold
+	MaxThreadgateRules = 5
+)
+
+// threadgateRuleTypes maps the rule names used by models.ReplyGate to their
+// lexicon types
+var threadgateRuleTypes = map[string]string{
+	models.ReplyAllowMentioned: "app.bsky.feed.threadgate#mentionRule",
+	models.ReplyAllowFollowers: "app.bsky.feed.threadgate#followerRule",
+	models.ReplyAllowFollowing: "app.bsky.feed.threadgate#followingRule",
+}
+
+// SetThreadgate writes the threadgate limiting who can reply to the thread
+// started by post, or removes it when gate is nil so everyone can reply.
+// Replies that were hidden through the existing threadgate stay hidden
+func (rm *RecordManager) SetThreadgate(post *ATURI, gate *models.ReplyGate) error {
+	gateURI := &ATURI{Repo: post.Repo, Collection: ThreadgateCollection, RKey: post.RKey}
+
+	var existing models.ThreadgateRecord
+	found, err := rm.getRecordValue(gateURI, &existing)
+	if err != nil {
+		return fmt.Errorf("failed to fetch threadgate: %w", err)
+	}
+
+	if gate == nil && len(existing.HiddenReplies) == 0 {
+		if !found {
+			return nil
+		}
+		logger.Debugf("Removing threadgate of %s", post)
+		return rm.DeleteRecord(gateURI)
+	}
+
+	record := models.ThreadgateRecord{
+		Type:          ThreadgateCollection,
+		Post:          post.String(),
+		CreatedAt:     time.Now().UTC(),
+		HiddenReplies: existing.HiddenReplies,
+	}
+
+	if gate != nil {
+		// An empty allow list lets nobody reply
+		allow := []models.ThreadgateRule{}
+		for _, rule := range gate.Allow {
+			if ruleType, ok := threadgateRuleTypes[rule]; ok {
+				allow = append(allow, models.ThreadgateRule{Type: ruleType})
+			}
+		}
+		for _, list := range gate.Lists {
+			allow = append(allow, models.ThreadgateRule{
+				Type: "app.bsky.feed.threadgate#listRule",
+				List: list,
+			})
+		}
+		record.Allow = &allow
+		logger.Debugf("Writing threadgate of %s with %d rule(s)", post, len(allow))
+	} else {
+		logger.Debugf("Writing threadgate of %s without rules to keep its hidden replies", post)
+	}
+
+	return rm.PutRecord(gateURI, record)
+}
+
+// SetPostgate writes or removes the postgate that stops post from being
+// quoted. Quotes that were detached through the existing postgate stay
+// detached
+func (rm *RecordManager) SetPostgate(post *ATURI, disableQuotes bool) error {
+	gateURI := &ATURI{Repo: post.Repo, Collection: PostgateCollection, RKey: post.RKey}
+
+	var existing models.PostgateRecord
+	found, err := rm.getRecordValue(gateURI, &existing)
+	if err != nil {
+		return fmt.Errorf("failed to fetch postgate: %w", err)
+	}
+
+	if !disableQuotes && len(existing.DetachedEmbeddingURIs) == 0 {
+		if !found {
+			return nil
+		}
+		logger.Debugf("Removing postgate of %s", post)
+		return rm.DeleteRecord(gateURI)
+	}
+
+	record := models.PostgateRecord{
+		Type:                  PostgateCollection,
+		Post:                  post.String(),
+		CreatedAt:             time.Now().UTC(),
+		DetachedEmbeddingURIs: existing.DetachedEmbeddingURIs,
+	}
+	if disableQuotes {
+		record.EmbeddingRules = []models.PostgateRule{{Type: "app.bsky.feed.postgate#disableRule"}}
+	}
+
+	logger.Debugf("Writing postgate of %s (quotes disabled: %v)", post, disableQuotes)
+
+	return rm.PutRecord(gateURI, record)
+}
+
+// getRecordValue decodes the record at uri into value, reporting false when
+// there is no such record
+func (rm *RecordManager) getRecordValue(uri *ATURI, value any) (bool, error) {
+	current, err := rm.GetRecord(uri)
+
+	var xrpcErr *XRPCError
+	if errors.As(err, &xrpcErr) && (xrpcErr.Name == "RecordNotFound" || xrpcErr.StatusCode == http.StatusNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := json.Unmarshal(current.Value, value); err != nil {
+		return false, fmt.Errorf("failed to decode record: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,122 @@
+package atproto
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/think-root/bluesky-connector/internal/models"
+)
+
+// gateServer fakes the repository endpoints, serving existing records and
+// recording what was written and deleted
+type gateServer struct {
+	existing map[string]string
+	put      map[string]json.RawMessage
+	deleted  []string
+}
+
+func newGateServer(t *testing.T, existing map[string]string) (*gateServer, *RecordManager) {
+	gs := &gateServer{existing: existing, put: make(map[string]json.RawMessage)}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case GetRecordEndpoint:
+			value, ok := gs.existing[r.URL.Query().Get("collection")]
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "RecordNotFound", "message": "Could not locate record"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"uri": "at://x", "cid": "cid", "value": json.RawMessage(value)})
+		case PutRecordEndpoint:
+			var req struct {
+				Collection string          `json:"collection"`
+				RKey       string          `json:"rkey"`
+				Record     json.RawMessage `json:"record"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, "3k2a", req.RKey)
+			gs.put[req.Collection] = req.Record
+			json.NewEncoder(w).Encode(map[string]string{"uri": "at://x", "cid": "cid"})
+		case DeleteRecordEndpoint:
+			var req models.DeleteRecordRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			gs.deleted = append(gs.deleted, req.Collection)
+			w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	sm := NewSessionManager(server.URL)
+	sm.accessToken = "token"
+	return gs, NewRecordManager(server.URL, sm, nil)
+}
+
+var gatedPost = &ATURI{Repo: "did:plc:me", Collection: PostCollection, RKey: "3k2a"}
+
+func TestSetThreadgate(t *testing.T) {
+	t.Run("rules and lists", func(t *testing.T) {
+		gs, rm := newGateServer(t, nil)
+
+		require.NoError(t, rm.SetThreadgate(gatedPost, &models.ReplyGate{
+			Allow: []string{models.ReplyAllowMentioned, models.ReplyAllowFollowers},
+			Lists: []string{"at://did:plc:me/app.bsky.graph.list/abc"},
+		}))
+
+		var record models.ThreadgateRecord
+		require.NoError(t, json.Unmarshal(gs.put[ThreadgateCollection], &record))
+		assert.Equal(t, "at://did:plc:me/app.bsky.feed.post/3k2a", record.Post)
+		require.NotNil(t, record.Allow)
+		assert.Equal(t, []models.ThreadgateRule{
+			{Type: "app.bsky.feed.threadgate#mentionRule"},
+			{Type: "app.bsky.feed.threadgate#followerRule"},
+			{Type: "app.bsky.feed.threadgate#listRule", List: "at://did:plc:me/app.bsky.graph.list/abc"},
+		}, *record.Allow)
+	})
+
+	t.Run("nobody writes an empty allow list", func(t *testing.T) {
+		gs, rm := newGateServer(t, nil)
+
+		require.NoError(t, rm.SetThreadgate(gatedPost, &models.ReplyGate{}))
+		assert.Contains(t, string(gs.put[ThreadgateCollection]), `"allow":[]`)
+	})
+
+	t.Run("removing keeps hidden replies", func(t *testing.T) {
+		gs, rm := newGateServer(t, map[string]string{
+			ThreadgateCollection: `{"$type":"app.bsky.feed.threadgate","post":"at://x","allow":[],"createdAt":"2025-01-01T00:00:00Z","hiddenReplies":["at://did:plc:troll/app.bsky.feed.post/1"]}`,
+		})
+
+		require.NoError(t, rm.SetThreadgate(gatedPost, nil))
+		assert.NotContains(t, string(gs.put[ThreadgateCollection]), `"allow"`)
+		assert.Contains(t, string(gs.put[ThreadgateCollection]), "did:plc:troll")
+		assert.Empty(t, gs.deleted)
+	})
+
+	t.Run("removing deletes the record", func(t *testing.T) {
+		gs, rm := newGateServer(t, map[string]string{
+			ThreadgateCollection: `{"$type":"app.bsky.feed.threadgate","post":"at://x","allow":[],"createdAt":"2025-01-01T00:00:00Z"}`,
+		})
+
+		require.NoError(t, rm.SetThreadgate(gatedPost, nil))
+		assert.Equal(t, []string{ThreadgateCollection}, gs.deleted)
+	})
+}
+
+func TestSetPostgate(t *testing.T) {
+	gs, rm := newGateServer(t, nil)
+
+	require.NoError(t, rm.SetPostgate(gatedPost, true))
+	assert.Contains(t, string(gs.put[PostgateCollection]), "app.bsky.feed.postgate#disableRule")
+
+	// Nothing to remove
+	gs, rm = newGateServer(t, nil)
+	require.NoError(t, rm.SetPostgate(gatedPost, false))
+	assert.Empty(t, gs.put)
+	assert.Empty(t, gs.deleted)
+}
@@ -0,0 +1,105 @@
+package atproto
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/think-root/bluesky-connector/internal/models"
+)
+
+const (
+	ResolveHandleEndpoint = "/xrpc/com.atproto.identity.resolveHandle"
+
+	// HandleCacheTTL is how long a resolved handle is trusted before it is
+	// looked up again; handles that failed to resolve are retried sooner
+	HandleCacheTTL       = time.Hour
+	FailedHandleCacheTTL = 5 * time.Minute
+)
+
+type cachedHandle struct {
+	did       string
+	err       error
+	expiresAt time.Time
+}
+
+type IdentityResolver struct {
+	baseURL    string
+	httpClient *http.Client
+
+	mu    sync.Mutex
+	cache map[string]cachedHandle
+}
+
+func NewIdentityResolver(baseURL string, sessionManager *SessionManager) *IdentityResolver {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	return &IdentityResolver{
+		baseURL:    baseURL,
+		httpClient: sessionManager.httpClient,
+		cache:      make(map[string]cachedHandle),
+	}
+}
+
+// ResolveHandle returns the DID a handle currently points to. Results are
+// cached, including handles the server reported as unresolvable
+func (ir *IdentityResolver) ResolveHandle(handle string) (string, error) {
+	handle = strings.ToLower(handle)
+
+	ir.mu.Lock()
+	cached, ok := ir.cache[handle]
+	ir.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.did, cached.err
+	}
+
+	did, err := ir.resolveHandle(handle)
+
+	// Only cache definite answers, not transport failures
+	var xrpcErr *XRPCError
+	switch {
+	case err == nil:
+		ir.store(handle, cachedHandle{did: did, expiresAt: time.Now().Add(HandleCacheTTL)})
+	case errors.As(err, &xrpcErr) && xrpcErr.StatusCode == http.StatusBadRequest:
+		ir.store(handle, cachedHandle{err: err, expiresAt: time.Now().Add(FailedHandleCacheTTL)})
+	}
+
+	return did, err
+}
+
+func (ir *IdentityResolver) store(handle string, entry cachedHandle) {
+	ir.mu.Lock()
+	defer ir.mu.Unlock()
+	ir.cache[handle] = entry
+}
+
+func (ir *IdentityResolver) resolveHandle(handle string) (string, error) {
+	req, err := http.NewRequest("GET", ir.baseURL+ResolveHandleEndpoint+"?handle="+url.QueryEscape(handle), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := ir.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", newXRPCError(resp)
+	}
+
+	var resolveResp models.ResolveHandleResponse
+	if err := json.NewDecoder(resp.Body).Decode(&resolveResp); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return resolveResp.DID, nil
+}
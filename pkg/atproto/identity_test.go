@@ -0,0 +1,44 @@
+package atproto
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentityResolver_ResolveHandleCaches(t *testing.T) {
+	var calls int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		assert.Equal(t, ResolveHandleEndpoint, r.URL.Path)
+
+		switch r.URL.Query().Get("handle") {
+		case "alice.bsky.social":
+			json.NewEncoder(w).Encode(map[string]string{"did": "did:plc:alice"})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "InvalidRequest", "message": "Unable to resolve handle"})
+		}
+	}))
+	defer server.Close()
+
+	ir := NewIdentityResolver(server.URL, NewSessionManager(server.URL))
+
+	for range 2 {
+		did, err := ir.ResolveHandle("Alice.bsky.social")
+		assert.NoError(t, err)
+		assert.Equal(t, "did:plc:alice", did)
+	}
+
+	for range 2 {
+		_, err := ir.ResolveHandle("ghost.bsky.social")
+		assert.ErrorContains(t, err, "Unable to resolve handle")
+	}
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,55 @@
+package atproto
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/think-root/bluesky-connector/internal/logger"
+	"github.com/think-root/bluesky-connector/internal/models"
+)
+
+const (
+	LikeCollection   = "app.bsky.feed.like"
+	RepostCollection = "app.bsky.feed.repost"
+)
+
+// CreateRecord writes a new record to a collection of the repository, letting
+// the PDS pick its record key
+func (rm *RecordManager) CreateRecord(repo, collection string, record any) (*models.CreateRecordResponse, error) {
+	if !rm.sessionManager.IsAuthenticated() {
+		return nil, fmt.Errorf("not authenticated")
+	}
+
+	reqBody := models.CreateRecordRequest{
+		Repo:       repo,
+		Collection: collection,
+		Record:     record,
+	}
+
+	var recordResp models.CreateRecordResponse
+	if err := rm.call("POST", CreateRecordEndpoint, reqBody, &recordResp); err != nil {
+		return nil, err
+	}
+
+	return &recordResp, nil
+}
+
+// Like writes an app.bsky.feed.like record for the post
+func (rm *RecordManager) Like(repo string, subject models.PostRef) (*models.CreateRecordResponse, error) {
+	return rm.createSubjectRecord(repo, LikeCollection, subject)
+}
+
+// Repost writes an app.bsky.feed.repost record for the post
+func (rm *RecordManager) Repost(repo string, subject models.PostRef) (*models.CreateRecordResponse, error) {
+	return rm.createSubjectRecord(repo, RepostCollection, subject)
+}
+
+func (rm *RecordManager) createSubjectRecord(repo, collection string, subject models.PostRef) (*models.CreateRecordResponse, error) {
+	logger.Debugf("Creating %s record for %s", collection, subject.URI)
+
+	return rm.CreateRecord(repo, collection, models.SubjectRecord{
+		Type:      collection,
+		Subject:   subject,
+		CreatedAt: time.Now().UTC(),
+	})
+}
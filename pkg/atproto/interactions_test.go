@@ -0,0 +1,58 @@
+package atproto
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/think-root/bluesky-connector/internal/models"
+)
+
+func TestRecordManager_LikeAndRepost(t *testing.T) {
+	var created []models.CreateRecordRequest
+	var records []models.SubjectRecord
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, CreateRecordEndpoint, r.URL.Path)
+
+		var req struct {
+			models.CreateRecordRequest
+			Record models.SubjectRecord `json:"record"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		created = append(created, req.CreateRecordRequest)
+		records = append(records, req.Record)
+
+		json.NewEncoder(w).Encode(models.CreateRecordResponse{
+			URI: "at://did:plc:me/" + req.Collection + "/3k2b",
+			CID: "recordcid",
+		})
+	}))
+	defer server.Close()
+
+	sm := NewSessionManager(server.URL)
+	sm.accessToken = "token"
+	rm := NewRecordManager(server.URL, sm, nil)
+
+	subject := models.PostRef{URI: "at://did:plc:partner/app.bsky.feed.post/3k2a", CID: "postcid"}
+
+	like, err := rm.Like("did:plc:me", subject)
+	require.NoError(t, err)
+	assert.Equal(t, "at://did:plc:me/app.bsky.feed.like/3k2b", like.URI)
+
+	repost, err := rm.Repost("did:plc:me", subject)
+	require.NoError(t, err)
+	assert.Equal(t, "at://did:plc:me/app.bsky.feed.repost/3k2b", repost.URI)
+
+	require.Len(t, created, 2)
+	assert.Equal(t, LikeCollection, created[0].Collection)
+	assert.Equal(t, RepostCollection, created[1].Collection)
+	for i, record := range records {
+		assert.Equal(t, created[i].Collection, record.Type)
+		assert.Equal(t, subject, record.Subject)
+		assert.False(t, record.CreatedAt.IsZero())
+	}
+}
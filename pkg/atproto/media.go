@@ -4,10 +4,16 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
 	"io"
 	"net/http"
 
+	"github.com/think-root/bluesky-connector/internal/logger"
 	"github.com/think-root/bluesky-connector/internal/models"
+	_ "golang.org/x/image/webp"
 )
 
 const (
@@ -95,33 +101,118 @@ func (mm *MediaManager) uploadBlobWithRetry(data []byte, mimeType string, isRetr
 	return &uploadResp.Blob, nil
 }
 
-func (mm *MediaManager) CreateImageEmbed(imageData []byte, mimeType, altText string) (*models.Embed, error) {
-	fmt.Printf("DEBUG: CreateImageEmbed called with mimeType: %s\n", mimeType)
+// CreateImagesEmbed uploads up to models.MaxImagesPerPost images and returns
+// an app.bsky.embed.images embed holding them in the given order
+func (mm *MediaManager) CreateImagesEmbed(images []models.ImageUpload) (*models.Embed, error) {
+	if len(images) == 0 {
+		return nil, fmt.Errorf("no images to embed")
+	}
+	if len(images) > models.MaxImagesPerPost {
+		return nil, fmt.Errorf("too many images: %d (max %d)", len(images), models.MaxImagesPerPost)
+	}
+
+	embed := &models.Embed{
+		Type:   "app.bsky.embed.images",
+		Images: make([]models.EmbedImage, 0, len(images)),
+	}
+
+	for i, img := range images {
+		embedImage, err := mm.uploadImage(img.Data, DetectMimeType(img.Data), img.Alt)
+		if err != nil {
+			return nil, fmt.Errorf("image %d: %w", i+1, err)
+		}
+		embed.Images = append(embed.Images, *embedImage)
+	}
+
+	logger.Debugf("Created embed with %d image(s)", len(embed.Images))
+	return embed, nil
+}
+
+// PreviewImagesEmbed describes images the way CreateImagesEmbed would embed
+// them, without uploading them, so the image blob references are left empty
+func PreviewImagesEmbed(images []models.ImageUpload) *models.Embed {
+	embed := &models.Embed{
+		Type:   "app.bsky.embed.images",
+		Images: make([]models.EmbedImage, 0, len(images)),
+	}
+
+	for _, img := range images {
+		embedImage := models.EmbedImage{Alt: img.Alt}
+		if aspectRatio, err := DetectAspectRatio(img.Data); err == nil {
+			embedImage.AspectRatio = aspectRatio
+		}
+		embed.Images = append(embed.Images, embedImage)
+	}
+
+	return embed
+}
 
+// uploadImage uploads a single image blob and describes it for an images embed
+func (mm *MediaManager) uploadImage(imageData []byte, mimeType, altText string) (*models.EmbedImage, error) {
 	blobRef, err := mm.UploadBlob(imageData, mimeType)
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload blob: %w", err)
 	}
 
-	fmt.Printf("DEBUG: BlobRef after upload - Type: %s, MimeType: %s, Size: %d\n",
+	logger.Debugf("BlobRef after upload - Type: %s, MimeType: %s, Size: %d",
 		blobRef.Type, blobRef.MimeType, blobRef.Size)
 
 	if blobRef.Type == "" {
 		blobRef.Type = "blob"
 	}
 
-	embed := &models.Embed{
-		Type: "app.bsky.embed.images",
-		Images: []models.EmbedImage{
-			{
-				Alt:   altText,
-				Image: blobRef,
+	embedImage := &models.EmbedImage{
+		Alt:   altText,
+		Image: blobRef,
+	}
+
+	// Aspect ratio is optional, so an undecodable header only costs layout hints
+	if aspectRatio, err := DetectAspectRatio(imageData); err != nil {
+		logger.Debugf("Failed to detect image dimensions: %v", err)
+	} else {
+		embedImage.AspectRatio = aspectRatio
+	}
+
+	return embedImage, nil
+}
+
+// DetectAspectRatio reads the image header to find its pixel dimensions
+func DetectAspectRatio(data []byte) (*models.AspectRatio, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image header: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, fmt.Errorf("invalid image dimensions %dx%d", cfg.Width, cfg.Height)
+	}
+
+	return &models.AspectRatio{
+		Width:  cfg.Width,
+		Height: cfg.Height,
+	}, nil
+}
+
+// CreateQuoteEmbed embeds the quoted post. When the post also carries media,
+// both are combined into an app.bsky.embed.recordWithMedia
+func CreateQuoteEmbed(quoted *models.PostRef, media *models.Embed) *models.Embed {
+	if media == nil {
+		return &models.Embed{
+			Type: "app.bsky.embed.record",
+			Record: &models.EmbedRecord{
+				URI: quoted.URI,
+				CID: quoted.CID,
 			},
-		},
+		}
 	}
 
-	fmt.Printf("DEBUG: Created embed with image MIME type: %s\n", embed.Images[0].Image.MimeType)
-	return embed, nil
+	return &models.Embed{
+		Type: "app.bsky.embed.recordWithMedia",
+		Record: &models.EmbedRecord{
+			Type:   "app.bsky.embed.record",
+			Record: quoted,
+		},
+		Media: media,
+	}
 }
 
 func DetectMimeType(data []byte) string {
@@ -137,8 +228,14 @@ func DetectMimeType(data []byte) string {
 		return "image/png"
 	case bytes.HasPrefix(data, []byte{0x47, 0x49, 0x46}):
 		return "image/gif"
-	case bytes.HasPrefix(data, []byte{0x52, 0x49, 0x46, 0x46}) && bytes.Contains(data[8:12], []byte("WEBP")):
+	case bytes.HasPrefix(data, []byte{0x52, 0x49, 0x46, 0x46}) && len(data) >= 12 && bytes.Contains(data[8:12], []byte("WEBP")):
 		return "image/webp"
+	case len(data) >= 12 && bytes.Equal(data[4:8], []byte("ftyp")) && bytes.Equal(data[8:12], []byte("qt  ")):
+		return "video/quicktime"
+	case len(data) >= 8 && bytes.Equal(data[4:8], []byte("ftyp")):
+		return "video/mp4"
+	case bytes.HasPrefix(data, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return "video/webm"
 	default:
 		return "application/octet-stream"
 	}
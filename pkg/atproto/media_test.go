@@ -0,0 +1,71 @@
+package atproto
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/think-root/bluesky-connector/internal/models"
+)
+
+func TestDetectAspectRatio(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 640, 360))
+
+	var pngBuf bytes.Buffer
+	assert.NoError(t, png.Encode(&pngBuf, img))
+
+	var jpegBuf bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&jpegBuf, img, nil))
+
+	for name, data := range map[string][]byte{"PNG": pngBuf.Bytes(), "JPEG": jpegBuf.Bytes()} {
+		t.Run(name, func(t *testing.T) {
+			ratio, err := DetectAspectRatio(data)
+			assert.NoError(t, err)
+			assert.Equal(t, 640, ratio.Width)
+			assert.Equal(t, 360, ratio.Height)
+		})
+	}
+
+	t.Run("Not an image", func(t *testing.T) {
+		_, err := DetectAspectRatio([]byte("definitely not an image"))
+		assert.Error(t, err)
+	})
+}
+
+func TestCreateQuoteEmbed(t *testing.T) {
+	quoted := &models.PostRef{URI: "at://did:plc:abc123/app.bsky.feed.post/3k2a4b", CID: "bafyquoted"}
+
+	t.Run("Quote only", func(t *testing.T) {
+		data, err := json.Marshal(CreateQuoteEmbed(quoted, nil))
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"$type": "app.bsky.embed.record",
+			"record": {"uri": "at://did:plc:abc123/app.bsky.feed.post/3k2a4b", "cid": "bafyquoted"}
+		}`, string(data))
+	})
+
+	t.Run("Quote with images", func(t *testing.T) {
+		media := &models.Embed{
+			Type:   "app.bsky.embed.images",
+			Images: []models.EmbedImage{{Alt: "Chart", Image: &models.BlobRef{Type: "blob", Ref: "bafyimage", MimeType: "image/png", Size: 10}}},
+		}
+
+		data, err := json.Marshal(CreateQuoteEmbed(quoted, media))
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"$type": "app.bsky.embed.recordWithMedia",
+			"record": {
+				"$type": "app.bsky.embed.record",
+				"record": {"uri": "at://did:plc:abc123/app.bsky.feed.post/3k2a4b", "cid": "bafyquoted"}
+			},
+			"media": {
+				"$type": "app.bsky.embed.images",
+				"images": [{"alt": "Chart", "image": {"$type": "blob", "ref": "bafyimage", "mimeType": "image/png", "size": 10}}]
+			}
+		}`, string(data))
+	})
+}
@@ -1,6 +1,7 @@
 package atproto
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,6 +12,8 @@ import (
 	"github.com/think-root/bluesky-connector/internal/models"
 )
 
+var ErrMediaTooLarge = errors.New("media too large")
+
 // OpenGraphData holds the extracted Open Graph metadata
 type OpenGraphData struct {
 	Title       string
@@ -112,15 +115,30 @@ func extractTitleTag(html string) string {
 	return ""
 }
 
-// FetchImage downloads an image from URL
+// MaxFetchedImageSize is the largest image downloaded from a URL
+const MaxFetchedImageSize = 1024 * 1024
+
+// FetchImage downloads an image from URL, failing with ErrMediaTooLarge when
+// it is over MaxFetchedImageSize
 func FetchImage(url string) ([]byte, string, error) {
+	return fetchMedia(url, MaxFetchedImageSize, 15*time.Second)
+}
+
+// FetchVideo downloads a video from URL
+func FetchVideo(url string) ([]byte, string, error) {
+	return fetchMedia(url, MaxVideoSize, 5*time.Minute)
+}
+
+// fetchMedia downloads media of at most maxSize bytes, failing with
+// ErrMediaTooLarge rather than returning a truncated file
+func fetchMedia(url string, maxSize int64, timeout time.Duration) ([]byte, string, error) {
 	client := &http.Client{
-		Timeout: 15 * time.Second,
+		Timeout: timeout,
 	}
 
 	resp, err := client.Get(url)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to fetch image: %w", err)
+		return nil, "", fmt.Errorf("failed to fetch media: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -128,11 +146,18 @@ func FetchImage(url string) ([]byte, string, error) {
 		return nil, "", fmt.Errorf("HTTP error: %d", resp.StatusCode)
 	}
 
-	// Limit image size to 1MB
-	limitedReader := io.LimitReader(resp.Body, 1024*1024)
-	data, err := io.ReadAll(limitedReader)
+	if resp.ContentLength > maxSize {
+		return nil, "", fmt.Errorf("%w: %d bytes (max %d)", ErrMediaTooLarge, resp.ContentLength, maxSize)
+	}
+
+	// Read one byte past the limit to tell a file of exactly maxSize bytes
+	// from a larger one
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to read image: %w", err)
+		return nil, "", fmt.Errorf("failed to read media: %w", err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, "", fmt.Errorf("%w: more than %d bytes", ErrMediaTooLarge, maxSize)
 	}
 
 	mimeType := resp.Header.Get("Content-Type")
@@ -145,6 +170,31 @@ func FetchImage(url string) ([]byte, string, error) {
 
 // CreateExternalEmbed creates an external embed with OG metadata
 func (mm *MediaManager) CreateExternalEmbed(url string) (*models.Embed, error) {
+	embed, thumbURL := FetchExternalEmbed(url)
+
+	// Try to upload thumbnail if available
+	if thumbURL != "" {
+		imageData, mimeType, err := FetchImage(thumbURL)
+		if err != nil {
+			fmt.Printf("DEBUG: Failed to fetch OG image: %v, continuing without thumbnail\n", err)
+		} else {
+			blobRef, err := mm.UploadBlob(imageData, mimeType)
+			if err != nil {
+				fmt.Printf("DEBUG: Failed to upload thumbnail: %v, continuing without thumbnail\n", err)
+			} else {
+				embed.External.Thumb = blobRef
+				fmt.Println("DEBUG: Successfully uploaded thumbnail for external embed")
+			}
+		}
+	}
+
+	return embed, nil
+}
+
+// FetchExternalEmbed builds an external embed from the OG metadata of url
+// without uploading anything, returning the URL of the thumbnail image the
+// page suggests, if any
+func FetchExternalEmbed(url string) (*models.Embed, string) {
 	og, err := FetchOpenGraphData(url)
 	if err != nil {
 		fmt.Printf("DEBUG: Failed to fetch OG data: %v, using URL as fallback\n", err)
@@ -156,7 +206,7 @@ func (mm *MediaManager) CreateExternalEmbed(url string) (*models.Embed, error) {
 				Title:       url,
 				Description: "",
 			},
-		}, nil
+		}, ""
 	}
 
 	embed := &models.Embed{
@@ -168,21 +218,5 @@ func (mm *MediaManager) CreateExternalEmbed(url string) (*models.Embed, error) {
 		},
 	}
 
-	// Try to upload thumbnail if available
-	if og.Image != "" {
-		imageData, mimeType, err := FetchImage(og.Image)
-		if err != nil {
-			fmt.Printf("DEBUG: Failed to fetch OG image: %v, continuing without thumbnail\n", err)
-		} else {
-			blobRef, err := mm.UploadBlob(imageData, mimeType)
-			if err != nil {
-				fmt.Printf("DEBUG: Failed to upload thumbnail: %v, continuing without thumbnail\n", err)
-			} else {
-				embed.External.Thumb = blobRef
-				fmt.Println("DEBUG: Successfully uploaded thumbnail for external embed")
-			}
-		}
-	}
-
-	return embed, nil
+	return embed, og.Image
 }
@@ -0,0 +1,56 @@
+package atproto
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchMedia_SizeLimit(t *testing.T) {
+	const maxSize = 16
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+		if r.URL.Query().Get("chunked") == "" {
+			w.Header().Set("Content-Length", strconv.Itoa(size))
+		}
+		w.Header().Set("Content-Type", "video/mp4")
+		w.Write(bytes.Repeat([]byte{'v'}, size))
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}))
+	defer server.Close()
+
+	data, mimeType, err := fetchMedia(server.URL+"?size=16", maxSize, time.Second)
+	require.NoError(t, err)
+	assert.Len(t, data, maxSize)
+	assert.Equal(t, "video/mp4", mimeType)
+
+	// Rejected up front from Content-Length
+	_, _, err = fetchMedia(server.URL+"?size=17", maxSize, time.Second)
+	assert.ErrorIs(t, err, ErrMediaTooLarge)
+	assert.ErrorContains(t, err, "17 bytes (max 16)")
+
+	// Rejected while reading when the length is not announced
+	_, _, err = fetchMedia(server.URL+"?size=17&chunked=1", maxSize, time.Second)
+	assert.ErrorIs(t, err, ErrMediaTooLarge)
+	assert.ErrorContains(t, err, "more than 16 bytes")
+}
+
+func TestFetchImage_TooLarge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(make([]byte, MaxFetchedImageSize+1))
+	}))
+	defer server.Close()
+
+	_, _, err := FetchImage(server.URL)
+	assert.ErrorIs(t, err, ErrMediaTooLarge)
+}
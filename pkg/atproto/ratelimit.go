@@ -0,0 +1,231 @@
+package atproto
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/think-root/bluesky-connector/internal/logger"
+	"github.com/think-root/bluesky-connector/internal/models"
+)
+
+const (
+	DefaultRateLimitMaxWait = 15 * time.Second
+
+	// MaxRateLimitMaxWait is the longest wait that still leaves the request
+	// time to be sent, and retried after a 429, within RequestTimeout
+	MaxRateLimitMaxWait = RequestTimeout - 10*time.Second
+
+	// defaultRetryAfter is how long a 429 without Retry-After or
+	// ratelimit-reset is assumed to last
+	defaultRetryAfter = time.Minute
+)
+
+// RateLimitError is a request rejected because the host's rate limit is
+// exhausted for longer than the limiter is willing to wait
+type RateLimitError struct {
+	Host       string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited by %s, retry in %s", e.Host, e.RetryAfter.Round(time.Second))
+}
+
+// RateLimiter is an http.RoundTripper tracking the budget hosts report in
+// their ratelimit-limit, ratelimit-remaining and ratelimit-reset headers.
+// Requests to a host whose budget is used up wait for it to reset, or fail
+// with a *RateLimitError when that is more than maxWait away. A 429 is
+// retried once after its Retry-After under the same rule
+type RateLimiter struct {
+	transport http.RoundTripper
+	maxWait   time.Duration
+
+	mu      sync.Mutex
+	budgets map[string]*models.RateLimitStatus
+
+	// sleep is swapped out in tests
+	sleep func(context.Context, time.Duration) error
+}
+
+func NewRateLimiter(maxWait time.Duration) *RateLimiter {
+	return &RateLimiter{
+		transport: http.DefaultTransport,
+		maxWait:   maxWait,
+		budgets:   make(map[string]*models.RateLimitStatus),
+		sleep:     sleepContext,
+	}
+}
+
+func (rl *RateLimiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	if err := rl.wait(req.Context(), host, rl.waitFor(host)); err != nil {
+		return nil, err
+	}
+
+	resp, err := rl.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	wait := rl.update(host, resp)
+	if resp.StatusCode != http.StatusTooManyRequests {
+		return resp, nil
+	}
+
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	// Only requests whose body can be sent again are retried
+	if req.Body != nil && req.GetBody == nil {
+		return nil, &RateLimitError{Host: host, RetryAfter: wait}
+	}
+	if err := rl.wait(req.Context(), host, wait); err != nil {
+		return nil, err
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err = rl.transport.RoundTrip(retry)
+	if err != nil {
+		return nil, err
+	}
+
+	wait = rl.update(host, resp)
+	if resp.StatusCode == http.StatusTooManyRequests {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return nil, &RateLimitError{Host: host, RetryAfter: wait}
+	}
+
+	return resp, nil
+}
+
+// wait sleeps for d, or fails when d is longer than the limiter waits or ctx
+// ends first
+func (rl *RateLimiter) wait(ctx context.Context, host string, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	if d > rl.maxWait {
+		return &RateLimitError{Host: host, RetryAfter: d}
+	}
+
+	logger.Debugf("Rate limit of %s exhausted, waiting %v", host, d)
+	return rl.sleep(ctx, d)
+}
+
+// sleepContext sleeps for d, returning early with the error of ctx when it
+// ends first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// waitFor returns how long until the exhausted budget of host resets
+func (rl *RateLimiter) waitFor(host string) time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	budget, ok := rl.budgets[host]
+	if !ok || budget.Remaining > 0 {
+		return 0
+	}
+	return time.Until(budget.Reset)
+}
+
+// update records the budget a response reports and returns how long to wait
+// before retrying it when it is a 429
+func (rl *RateLimiter) update(host string, resp *http.Response) time.Duration {
+	now := time.Now()
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	budget := rl.budgets[host]
+	if limit, err := strconv.Atoi(resp.Header.Get("ratelimit-limit")); err == nil {
+		if budget == nil {
+			budget = &models.RateLimitStatus{Host: host}
+			rl.budgets[host] = budget
+		}
+		budget.Limit = limit
+		budget.Policy = resp.Header.Get("ratelimit-policy")
+		if remaining, err := strconv.Atoi(resp.Header.Get("ratelimit-remaining")); err == nil {
+			budget.Remaining = remaining
+		}
+		if reset, err := strconv.ParseInt(resp.Header.Get("ratelimit-reset"), 10, 64); err == nil {
+			budget.Reset = time.Unix(reset, 0)
+		}
+	}
+
+	if resp.StatusCode != http.StatusTooManyRequests {
+		return 0
+	}
+
+	wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now)
+	if !ok && budget != nil && budget.Reset.After(now) {
+		wait = budget.Reset.Sub(now)
+	} else if !ok {
+		wait = defaultRetryAfter
+	}
+
+	if budget == nil {
+		budget = &models.RateLimitStatus{Host: host}
+		rl.budgets[host] = budget
+	}
+	budget.Remaining = 0
+	if reset := now.Add(wait); reset.After(budget.Reset) {
+		budget.Reset = reset
+	}
+
+	logger.Debugf("Rate limited by %s for %v", host, wait)
+	return wait
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as a date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// Budgets returns the last reported budget of every host, by host
+func (rl *RateLimiter) Budgets() []models.RateLimitStatus {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	budgets := make([]models.RateLimitStatus, 0, len(rl.budgets))
+	for _, budget := range rl.budgets {
+		budgets = append(budgets, *budget)
+	}
+	sort.Slice(budgets, func(i, j int) bool {
+		return budgets[i].Host < budgets[j].Host
+	})
+	return budgets
+}
@@ -0,0 +1,162 @@
+package atproto
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRateLimiter(maxWait time.Duration) (*RateLimiter, *[]time.Duration) {
+	var slept []time.Duration
+	rl := NewRateLimiter(maxWait)
+	rl.sleep = func(_ context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+	return rl, &slept
+}
+
+func TestRateLimiter_TracksBudgetAndRejectsWhenExhausted(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Unix()
+	remaining := atomic.Int32{}
+	remaining.Store(1)
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("ratelimit-limit", "3000")
+		w.Header().Set("ratelimit-remaining", strconv.Itoa(int(remaining.Add(-1))))
+		w.Header().Set("ratelimit-reset", strconv.FormatInt(reset, 10))
+		w.Header().Set("ratelimit-policy", "3000;w=300")
+	}))
+	defer server.Close()
+
+	rl, slept := newTestRateLimiter(DefaultRateLimitMaxWait)
+	client := &http.Client{Transport: rl}
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	budgets := rl.Budgets()
+	require.Len(t, budgets, 1)
+	assert.Equal(t, strings.TrimPrefix(server.URL, "http://"), budgets[0].Host)
+	assert.Equal(t, 3000, budgets[0].Limit)
+	assert.Equal(t, 0, budgets[0].Remaining)
+	assert.Equal(t, reset, budgets[0].Reset.Unix())
+	assert.Equal(t, "3000;w=300", budgets[0].Policy)
+
+	// The budget resets in an hour, far beyond the wait allowed
+	_, err = client.Get(server.URL)
+	var rateLimitErr *RateLimitError
+	require.ErrorAs(t, err, &rateLimitErr)
+	assert.InDelta(t, time.Hour.Seconds(), rateLimitErr.RetryAfter.Seconds(), 5)
+	assert.Equal(t, int32(1), requests.Load())
+	assert.Empty(t, *slept)
+}
+
+func TestRateLimiter_RetriesAfterShort429(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":"RateLimitExceeded","message":"Rate Limit Exceeded"}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	rl, slept := newTestRateLimiter(DefaultRateLimitMaxWait)
+	client := &http.Client{Transport: rl}
+
+	resp, err := client.Post(server.URL, "application/json", strings.NewReader(`{"text":"hi"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{`{"text":"hi"}`, `{"text":"hi"}`}, bodies)
+	assert.Equal(t, []time.Duration{3 * time.Second}, *slept)
+}
+
+func TestRateLimiter_RejectsLong429(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	rl, slept := newTestRateLimiter(DefaultRateLimitMaxWait)
+	client := &http.Client{Transport: rl}
+
+	_, err := client.Get(server.URL)
+	var rateLimitErr *RateLimitError
+	require.ErrorAs(t, err, &rateLimitErr)
+	assert.Equal(t, 120*time.Second, rateLimitErr.RetryAfter)
+	assert.Contains(t, err.Error(), "retry in 2m0s")
+
+	// Later requests are rejected without reaching the host
+	_, err = client.Get(server.URL)
+	require.ErrorAs(t, err, &rateLimitErr)
+	assert.Equal(t, int32(1), requests.Load())
+	assert.Empty(t, *slept)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	wait, ok := parseRetryAfter("42", now)
+	assert.True(t, ok)
+	assert.Equal(t, 42*time.Second, wait)
+
+	wait, ok = parseRetryAfter(now.Add(time.Minute).Format(http.TimeFormat), now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, wait)
+
+	_, ok = parseRetryAfter("", now)
+	assert.False(t, ok)
+	_, ok = parseRetryAfter("soon", now)
+	assert.False(t, ok)
+}
+
+func TestRateLimiter_WaitEndsWithContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ratelimit-limit", "3000")
+		w.Header().Set("ratelimit-remaining", "0")
+		w.Header().Set("ratelimit-reset", strconv.FormatInt(time.Now().Add(10*time.Second).Unix(), 10))
+	}))
+	defer server.Close()
+
+	rl := NewRateLimiter(DefaultRateLimitMaxWait)
+	client := &http.Client{Transport: rl}
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// The budget is exhausted, so the next request waits until it is cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = client.Do(req)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "unexpected error: %v", err)
+	assert.Less(t, time.Since(start), time.Second)
+}
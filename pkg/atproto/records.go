@@ -3,15 +3,22 @@ package atproto
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"time"
 
+	"github.com/think-root/bluesky-connector/internal/logger"
 	"github.com/think-root/bluesky-connector/internal/models"
 )
 
 const (
 	CreateRecordEndpoint = "/xrpc/com.atproto.repo.createRecord"
+	DeleteRecordEndpoint = "/xrpc/com.atproto.repo.deleteRecord"
+	GetRecordEndpoint    = "/xrpc/com.atproto.repo.getRecord"
+	PutRecordEndpoint    = "/xrpc/com.atproto.repo.putRecord"
 	PostCollection       = "app.bsky.feed.post"
 )
 
@@ -19,9 +26,12 @@ type RecordManager struct {
 	baseURL        string
 	httpClient     *http.Client
 	sessionManager *SessionManager
+	handleResolver HandleResolver
 }
 
-func NewRecordManager(baseURL string, sessionManager *SessionManager) *RecordManager {
+// NewRecordManager creates a record manager. handleResolver is used to link
+// @mentions to their DIDs and may be nil to leave mentions as plain text
+func NewRecordManager(baseURL string, sessionManager *SessionManager, handleResolver HandleResolver) *RecordManager {
 	if baseURL == "" {
 		baseURL = DefaultBaseURL
 	}
@@ -30,27 +40,90 @@ func NewRecordManager(baseURL string, sessionManager *SessionManager) *RecordMan
 		baseURL:        baseURL,
 		httpClient:     sessionManager.httpClient,
 		sessionManager: sessionManager,
+		handleResolver: handleResolver,
 	}
 }
 
 func (rm *RecordManager) CreatePost(repo, text string, reply *models.Reply, embed *models.Embed) (*models.CreateRecordResponse, error) {
+	return rm.CreatePostRecord(repo, rm.NewPostRecord(text, reply, embed))
+}
+
+// CreatePostRecord writes a post record built with NewPostRecord, letting
+// callers fill in fields such as langs first
+func (rm *RecordManager) CreatePostRecord(repo string, postRecord models.PostRecord) (*models.CreateRecordResponse, error) {
 	if !rm.sessionManager.IsAuthenticated() {
 		return nil, fmt.Errorf("not authenticated")
 	}
 
-	// Log token usage for debugging
-	fmt.Printf("DEBUG: Creating post with token: %s...\n", rm.sessionManager.GetAccessToken()[:20])
-
-	return rm.createPostWithRetry(repo, text, reply, embed, false)
+	return rm.createPostWithRetry(repo, postRecord)
 }
 
-func (rm *RecordManager) createPostWithRetry(repo, text string, reply *models.Reply, embed *models.Embed, isRetry bool) (*models.CreateRecordResponse, error) {
+// createPostWithRetry writes the post through call, which refreshes an
+// expired token, and retries once more after an UpstreamFailure
+func (rm *RecordManager) createPostWithRetry(repo string, postRecord models.PostRecord) (*models.CreateRecordResponse, error) {
 	// Log embed details if present
-	if embed != nil && len(embed.Images) > 0 {
+	if embed := postRecord.Embed; embed != nil && len(embed.Images) > 0 {
 		fmt.Printf("DEBUG: Creating post with embed - Type: %s, Image MIME: %s\n",
 			embed.Type, embed.Images[0].Image.MimeType)
 	}
 
+	reqBody := models.CreateRecordRequest{
+		Repo:       repo,
+		Collection: PostCollection,
+		Record:     postRecord,
+	}
+
+	var recordResp models.CreateRecordResponse
+	err := rm.call("POST", CreateRecordEndpoint, reqBody, &recordResp)
+
+	var xrpcErr *XRPCError
+	if errors.As(err, &xrpcErr) && xrpcErr.Name == "UpstreamFailure" {
+		fmt.Println("DEBUG: UpstreamFailure detected, waiting 1s and retrying...")
+		time.Sleep(1 * time.Second)
+		err = rm.call("POST", CreateRecordEndpoint, reqBody, &recordResp)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Println("DEBUG: Post creation successful")
+	return &recordResp, nil
+}
+
+// NewPostRecord builds the post record CreatePost writes, with the hashtag,
+// link and mention facets of the text
+func (rm *RecordManager) NewPostRecord(text string, reply *models.Reply, embed *models.Embed) models.PostRecord {
+	return models.PostRecord{
+		Type:      "app.bsky.feed.post",
+		Text:      text,
+		CreatedAt: time.Now().UTC(),
+		Reply:     reply,
+		Embed:     embed,
+		Facets:    rm.detectFacets(text),
+	}
+}
+
+// NewSelfLabels builds the self-labels of a record from their values,
+// returning nil when there are none
+func NewSelfLabels(values []string) *models.SelfLabels {
+	var labels []models.SelfLabel
+	seen := make(map[string]bool)
+	for _, value := range values {
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		labels = append(labels, models.SelfLabel{Val: value})
+	}
+
+	if len(labels) == 0 {
+		return nil
+	}
+	return &models.SelfLabels{Type: "com.atproto.label.defs#selfLabels", Values: labels}
+}
+
+// detectFacets returns the hashtag, link and mention facets of text
+func (rm *RecordManager) detectFacets(text string) []models.RichTextFacet {
 	// Detect hashtags and create facets
 	facets := DetectHashtags(text)
 	if len(facets) > 0 {
@@ -64,92 +137,207 @@ func (rm *RecordManager) createPostWithRetry(repo, text string, reply *models.Re
 		facets = append(facets, linkFacets...)
 	}
 
-	postRecord := models.PostRecord{
-		Type:      "app.bsky.feed.post",
-		Text:      text,
-		CreatedAt: time.Now().UTC(),
-		Reply:     reply,
-		Embed:     embed,
-		Facets:    facets,
+	// Detect mentions and add to facets
+	mentionFacets := DetectMentions(text, rm.handleResolver)
+	if len(mentionFacets) > 0 {
+		logger.Debugf("Detected %d mention(s) in post", len(mentionFacets))
+		facets = append(facets, mentionFacets...)
 	}
 
-	reqBody := models.CreateRecordRequest{
-		Repo:       repo,
-		Collection: PostCollection,
-		Record:     postRecord,
+	return facets
+}
+
+func (rm *RecordManager) CreateReply(repo, text string, root, parent *models.PostRef) (*models.CreateRecordResponse, error) {
+	reply := &models.Reply{
+		Root:   root,
+		Parent: parent,
 	}
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	return rm.CreatePost(repo, text, reply, nil)
+}
+
+func (rm *RecordManager) CreatePostWithEmbed(repo, text string, embed *models.Embed) (*models.CreateRecordResponse, error) {
+	return rm.CreatePost(repo, text, nil, embed)
+}
+
+// DeleteRecord removes a record from the repository. Deleting a record that
+// does not exist succeeds
+func (rm *RecordManager) DeleteRecord(uri *ATURI) error {
+	if !rm.sessionManager.IsAuthenticated() {
+		return fmt.Errorf("not authenticated")
+	}
+
+	reqBody := models.DeleteRecordRequest{
+		Repo:       uri.Repo,
+		Collection: uri.Collection,
+		RKey:       uri.RKey,
 	}
 
-	// Log payload for debugging
-	fmt.Printf("DEBUG: Request Payload: %s\n", string(jsonData))
+	logger.Debugf("Deleting record %s", uri)
 
-	req, err := http.NewRequest("POST", rm.baseURL+CreateRecordEndpoint, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	return rm.call("POST", DeleteRecordEndpoint, reqBody, nil)
+}
+
+// GetRecord fetches a record and the CID of its current version
+func (rm *RecordManager) GetRecord(uri *ATURI) (*models.GetRecordResponse, error) {
+	if !rm.sessionManager.IsAuthenticated() {
+		return nil, fmt.Errorf("not authenticated")
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+rm.sessionManager.GetAccessToken())
+	params := url.Values{}
+	params.Set("repo", uri.Repo)
+	params.Set("collection", uri.Collection)
+	params.Set("rkey", uri.RKey)
 
-	fmt.Printf("DEBUG: Making create post request (retry=%v)\n", isRetry)
+	var recordResp models.GetRecordResponse
+	if err := rm.call("GET", GetRecordEndpoint+"?"+params.Encode(), nil, &recordResp); err != nil {
+		return nil, err
+	}
 
-	resp, err := rm.httpClient.Do(req)
+	return &recordResp, nil
+}
+
+// PutRecord creates or replaces the record at uri
+func (rm *RecordManager) PutRecord(uri *ATURI, record any) error {
+	if !rm.sessionManager.IsAuthenticated() {
+		return fmt.Errorf("not authenticated")
+	}
+
+	reqBody := models.PutRecordRequest{
+		Repo:       uri.Repo,
+		Collection: uri.Collection,
+		RKey:       uri.RKey,
+		Record:     record,
+	}
+
+	return rm.call("POST", PutRecordEndpoint, reqBody, nil)
+}
+
+// EditPost replaces the text of a post, recomputing its facets, and its embed
+// when replaceEmbed is set (a nil embed removes it). The reply references,
+// createdAt and any other field are kept. The write only succeeds if the post
+// has not changed since it was read, so concurrent edits cannot overwrite
+// each other: the loser gets an InvalidSwap error
+func (rm *RecordManager) EditPost(uri *ATURI, text string, embed *models.Embed, replaceEmbed bool) (*models.CreateRecordResponse, error) {
+	current, err := rm.GetRecord(uri)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return nil, fmt.Errorf("failed to fetch post: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		var atError models.ATProtoError
-		if err := json.NewDecoder(resp.Body).Decode(&atError); err == nil {
-			fmt.Printf("DEBUG: AT Protocol error: %s (retry=%v)\n", atError.String(), isRetry)
-
-			// If token expired and this is not a retry, attempt to refresh and retry
-			if atError.Error == "ExpiredToken" && !isRetry {
-				fmt.Println("DEBUG: Token expired, attempting to refresh session...")
-				if _, refreshErr := rm.sessionManager.RefreshSession(); refreshErr != nil {
-					fmt.Printf("DEBUG: Failed to refresh session: %v\n", refreshErr)
-					return nil, fmt.Errorf("AT Protocol error: %s (failed to refresh: %v)", atError.String(), refreshErr)
-				}
-				fmt.Println("DEBUG: Session refreshed successfully, retrying post creation...")
-				return rm.createPostWithRetry(repo, text, reply, embed, true)
-			}
+	// Edit the record field by field so fields this package does not model
+	// survive the round trip unchanged
+	var record map[string]json.RawMessage
+	if err := json.Unmarshal(current.Value, &record); err != nil {
+		return nil, fmt.Errorf("failed to decode post: %w", err)
+	}
 
-			// Retry on UpstreamFailure
-			if atError.Error == "UpstreamFailure" && !isRetry {
-				fmt.Println("DEBUG: UpstreamFailure detected, waiting 1s and retrying...")
-				time.Sleep(1 * time.Second)
-				return rm.createPostWithRetry(repo, text, reply, embed, true)
-			}
+	set := func(field string, value any) error {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", field, err)
+		}
+		record[field] = data
+		return nil
+	}
+
+	if err := set("text", text); err != nil {
+		return nil, err
+	}
 
-			return nil, fmt.Errorf("AT Protocol error: %s", atError.String())
+	delete(record, "facets")
+	if facets := rm.detectFacets(text); len(facets) > 0 {
+		if err := set("facets", facets); err != nil {
+			return nil, err
 		}
-		return nil, fmt.Errorf("HTTP error: %d", resp.StatusCode)
 	}
 
-	fmt.Println("DEBUG: Post creation successful")
+	if replaceEmbed {
+		delete(record, "embed")
+		if embed != nil {
+			if err := set("embed", embed); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	reqBody := models.PutRecordRequest{
+		Repo:       uri.Repo,
+		Collection: uri.Collection,
+		RKey:       uri.RKey,
+		Record:     record,
+		SwapRecord: current.CID,
+	}
+
+	logger.Debugf("Editing post %s (swap %s)", uri, current.CID)
 
 	var recordResp models.CreateRecordResponse
-	if err := json.NewDecoder(resp.Body).Decode(&recordResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := rm.call("POST", PutRecordEndpoint, reqBody, &recordResp); err != nil {
+		return nil, err
 	}
 
 	return &recordResp, nil
 }
 
-func (rm *RecordManager) CreateReply(repo, text string, root, parent *models.PostRef) (*models.CreateRecordResponse, error) {
-	reply := &models.Reply{
-		Root:   root,
-		Parent: parent,
+// call makes an authenticated XRPC request, sending body as JSON when it is
+// not nil and decoding the response into out when it is not nil. An expired
+// access token is refreshed and the request retried once
+func (rm *RecordManager) call(method, endpoint string, body, out any) error {
+	var jsonData []byte
+	if body != nil {
+		var err error
+		jsonData, err = json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
 	}
 
-	return rm.CreatePost(repo, text, reply, nil)
+	err := rm.do(method, endpoint, jsonData, out)
+
+	var xrpcErr *XRPCError
+	if errors.As(err, &xrpcErr) && xrpcErr.Name == "ExpiredToken" {
+		logger.Debug("Token expired, attempting to refresh session...")
+		if _, refreshErr := rm.sessionManager.RefreshSession(); refreshErr != nil {
+			return fmt.Errorf("%w (failed to refresh: %v)", err, refreshErr)
+		}
+		err = rm.do(method, endpoint, jsonData, out)
+	}
+
+	return err
 }
 
-func (rm *RecordManager) CreatePostWithEmbed(repo, text string, embed *models.Embed) (*models.CreateRecordResponse, error) {
-	return rm.CreatePost(repo, text, nil, embed)
+func (rm *RecordManager) do(method, endpoint string, jsonData []byte, out any) error {
+	var reqBody io.Reader
+	if jsonData != nil {
+		reqBody = bytes.NewReader(jsonData)
+	}
+
+	req, err := http.NewRequest(method, rm.baseURL+endpoint, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if jsonData != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+rm.sessionManager.GetAccessToken())
+
+	resp, err := rm.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return newXRPCError(resp)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
 }
@@ -0,0 +1,154 @@
+package atproto
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/think-root/bluesky-connector/internal/models"
+)
+
+func TestRecordManager_EditPostKeepsRecordFields(t *testing.T) {
+	original := `{
+		"$type": "app.bsky.feed.post",
+		"text": "Helo #world",
+		"createdAt": "2025-01-02T03:04:05.000Z",
+		"langs": ["en"],
+		"reply": {
+			"root": {"uri": "at://did:plc:me/app.bsky.feed.post/root", "cid": "rootcid"},
+			"parent": {"uri": "at://did:plc:me/app.bsky.feed.post/root", "cid": "rootcid"}
+		},
+		"facets": [{"index": {"byteStart": 5, "byteEnd": 11}, "features": [{"$type": "app.bsky.richtext.facet#tag", "tag": "world"}]}],
+		"embed": {"$type": "app.bsky.embed.external", "external": {"uri": "https://example.com", "title": "", "description": ""}}
+	}`
+
+	var put struct {
+		models.PutRecordRequest
+		Record map[string]json.RawMessage `json:"record"`
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case GetRecordEndpoint:
+			assert.Equal(t, "did:plc:me", r.URL.Query().Get("repo"))
+			assert.Equal(t, "3k2a", r.URL.Query().Get("rkey"))
+			json.NewEncoder(w).Encode(map[string]any{
+				"uri":   "at://did:plc:me/app.bsky.feed.post/3k2a",
+				"cid":   "oldcid",
+				"value": json.RawMessage(original),
+			})
+		case PutRecordEndpoint:
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&put))
+			json.NewEncoder(w).Encode(map[string]string{
+				"uri": "at://did:plc:me/app.bsky.feed.post/3k2a",
+				"cid": "newcid",
+			})
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	sm := NewSessionManager(server.URL)
+	sm.accessToken = "token"
+	rm := NewRecordManager(server.URL, sm, nil)
+
+	uri := &ATURI{Repo: "did:plc:me", Collection: PostCollection, RKey: "3k2a"}
+	result, err := rm.EditPost(uri, "Hello https://example.com", nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, "newcid", result.CID)
+
+	assert.Equal(t, "oldcid", put.SwapRecord)
+	assert.Equal(t, "3k2a", put.RKey)
+	assert.JSONEq(t, `"Hello https://example.com"`, string(put.Record["text"]))
+	assert.JSONEq(t, `"2025-01-02T03:04:05.000Z"`, string(put.Record["createdAt"]))
+	assert.JSONEq(t, `["en"]`, string(put.Record["langs"]))
+	assert.Contains(t, string(put.Record["reply"]), "rootcid")
+	assert.Contains(t, string(put.Record["embed"]), "app.bsky.embed.external")
+
+	// The hashtag facet is gone and a link facet took its place
+	var facets []models.RichTextFacet
+	require.NoError(t, json.Unmarshal(put.Record["facets"], &facets))
+	require.Len(t, facets, 1)
+	assert.Equal(t, "https://example.com", facets[0].Features[0].URI)
+
+	// Removing the embed
+	put.Record = nil
+	_, err = rm.EditPost(uri, "Hello", nil, true)
+	require.NoError(t, err)
+	assert.NotContains(t, put.Record, "embed")
+	assert.NotContains(t, put.Record, "facets")
+}
+
+func TestRecordManager_EditPostSwapConflict(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case GetRecordEndpoint:
+			json.NewEncoder(w).Encode(map[string]any{
+				"uri":   "at://did:plc:me/app.bsky.feed.post/3k2a",
+				"cid":   "oldcid",
+				"value": map[string]string{"$type": "app.bsky.feed.post", "text": "a"},
+			})
+		case PutRecordEndpoint:
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "InvalidSwap", "message": "Record was at bafy..."})
+		}
+	}))
+	defer server.Close()
+
+	sm := NewSessionManager(server.URL)
+	sm.accessToken = "token"
+	rm := NewRecordManager(server.URL, sm, nil)
+
+	_, err := rm.EditPost(&ATURI{Repo: "did:plc:me", Collection: PostCollection, RKey: "3k2a"}, "b", nil, false)
+
+	var xrpcErr *XRPCError
+	require.ErrorAs(t, err, &xrpcErr)
+	assert.Equal(t, "InvalidSwap", xrpcErr.Name)
+}
+
+func TestRecordManager_CreatePostRateLimited(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, CreateRecordEndpoint, r.URL.Path)
+		w.Header().Set("Retry-After", "42")
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(map[string]string{"error": "RateLimitExceeded", "message": "Rate Limit Exceeded"})
+	}))
+	defer server.Close()
+
+	sm := NewSessionManager(server.URL)
+	sm.accessToken = "token"
+	// Without the limiter the 429 reaches the record manager itself
+	sm.httpClient.Transport = http.DefaultTransport
+	rm := NewRecordManager(server.URL, sm, nil)
+
+	_, err := rm.CreatePostRecord("did:plc:me", rm.NewPostRecord("hello", nil, nil))
+
+	var rateLimitErr *RateLimitError
+	require.ErrorAs(t, err, &rateLimitErr)
+	assert.Equal(t, 42*time.Second, rateLimitErr.RetryAfter)
+
+	var xrpcErr *XRPCError
+	require.ErrorAs(t, err, &xrpcErr)
+	assert.Equal(t, "RateLimitExceeded", xrpcErr.Name)
+	assert.Equal(t, 1, requests)
+}
+
+func TestNewSelfLabels(t *testing.T) {
+	assert.Nil(t, NewSelfLabels(nil))
+
+	labels := NewSelfLabels([]string{"graphic-media", "nudity", "graphic-media"})
+	assert.Equal(t, &models.SelfLabels{
+		Type: "com.atproto.label.defs#selfLabels",
+		Values: []models.SelfLabel{
+			{Val: "graphic-media"},
+			{Val: "nudity"},
+		},
+	}, labels)
+}
@@ -4,9 +4,20 @@ import (
 	"regexp"
 	"unicode/utf8"
 
+	"github.com/rivo/uniseg"
+	"github.com/think-root/bluesky-connector/internal/logger"
 	"github.com/think-root/bluesky-connector/internal/models"
 )
 
+// MaxPostGraphemes is the longest post text Bluesky accepts, measured in
+// grapheme clusters (user-perceived characters) rather than bytes or runes
+const MaxPostGraphemes = 300
+
+// GraphemeLength returns the length of text as Bluesky counts it
+func GraphemeLength(text string) int {
+	return uniseg.GraphemeClusterCount(text)
+}
+
 // DetectHashtags finds hashtags in text and returns facets for them
 // Hashtags must:
 // - Start with # followed by a non-digit, non-space character
@@ -113,6 +124,62 @@ func DetectLinks(text string) []models.RichTextFacet {
 	return facets
 }
 
+// HandleResolver resolves a handle to the DID it belongs to
+type HandleResolver interface {
+	ResolveHandle(handle string) (string, error)
+}
+
+var (
+	mentionRegex = regexp.MustCompile(`(?:^|[\s(])(@[a-zA-Z0-9.-]+)`)
+	handleRegex  = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)+[a-zA-Z]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+)
+
+// DetectMentions finds @handle mentions in text and returns facets linking
+// them to the DIDs the resolver returns. Mentions whose handle is invalid or
+// cannot be resolved are left as plain text
+func DetectMentions(text string, resolver HandleResolver) []models.RichTextFacet {
+	var facets []models.RichTextFacet
+
+	if resolver == nil {
+		return facets
+	}
+
+	matches := mentionRegex.FindAllStringSubmatchIndex(text, -1)
+
+	for _, match := range matches {
+		// match[2] and match[3] are the start and end of the mention (group 1)
+		mentionStart := match[2]
+		mention := stripTrailingPunctuation(text[mentionStart:match[3]])
+		mentionEnd := mentionStart + len(mention)
+
+		handle := mention[1:]
+		if len(handle) > 253 || !handleRegex.MatchString(handle) {
+			continue
+		}
+
+		did, err := resolver.ResolveHandle(handle)
+		if err != nil || did == "" {
+			logger.Debugf("Leaving mention @%s as plain text: %v", handle, err)
+			continue
+		}
+
+		facets = append(facets, models.RichTextFacet{
+			Index: models.ByteSlice{
+				ByteStart: mentionStart,
+				ByteEnd:   mentionEnd,
+			},
+			Features: []models.Feature{
+				{
+					Type: "app.bsky.richtext.facet#mention",
+					DID:  did,
+				},
+			},
+		})
+	}
+
+	return facets
+}
+
 // stripTrailingPunctuation removes trailing punctuation from a string
 func stripTrailingPunctuation(s string) string {
 	// Remove common trailing punctuation
@@ -1,11 +1,17 @@
 package atproto
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/think-root/bluesky-connector/internal/logger"
 )
 
+func init() {
+	logger.Init("error")
+}
+
 func TestDetectHashtags(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -152,3 +158,85 @@ func TestStripTrailingPunctuation(t *testing.T) {
 		})
 	}
 }
+
+type fakeHandleResolver map[string]string
+
+func (f fakeHandleResolver) ResolveHandle(handle string) (string, error) {
+	if did, ok := f[handle]; ok {
+		return did, nil
+	}
+	return "", fmt.Errorf("unable to resolve handle %s", handle)
+}
+
+func TestDetectMentions(t *testing.T) {
+	resolver := fakeHandleResolver{
+		"alice.bsky.social": "did:plc:alice",
+		"bob.example.com":   "did:plc:bob",
+	}
+
+	tests := []struct {
+		name          string
+		text          string
+		expectedDIDs  []string
+		expectedStart []int
+		expectedEnd   []int
+	}{
+		{
+			name:          "Single mention",
+			text:          "Thanks @alice.bsky.social for the review",
+			expectedDIDs:  []string{"did:plc:alice"},
+			expectedStart: []int{7},
+			expectedEnd:   []int{25},
+		},
+		{
+			name:          "Mention at start with trailing punctuation",
+			text:          "@bob.example.com, welcome!",
+			expectedDIDs:  []string{"did:plc:bob"},
+			expectedStart: []int{0},
+			expectedEnd:   []int{16},
+		},
+		{
+			name:          "Mention in parentheses after emoji",
+			text:          "🧵 (@alice.bsky.social)",
+			expectedDIDs:  []string{"did:plc:alice"},
+			expectedStart: []int{6},
+			expectedEnd:   []int{24},
+		},
+		{
+			name:          "Unresolvable handle stays plain text",
+			text:          "Hi @ghost.bsky.social and @alice.bsky.social",
+			expectedDIDs:  []string{"did:plc:alice"},
+			expectedStart: []int{26},
+			expectedEnd:   []int{44},
+		},
+		{
+			name:         "Email address is not a mention",
+			text:         "Write to team@alice.bsky.social",
+			expectedDIDs: []string{},
+		},
+		{
+			name:         "Handle without a dot is invalid",
+			text:         "Hello @alice",
+			expectedDIDs: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			facets := DetectMentions(tt.text, resolver)
+
+			assert.Equal(t, len(tt.expectedDIDs), len(facets), "Number of facets should match")
+
+			for i, facet := range facets {
+				assert.Equal(t, "app.bsky.richtext.facet#mention", facet.Features[0].Type)
+				assert.Equal(t, tt.expectedDIDs[i], facet.Features[0].DID)
+				assert.Equal(t, tt.expectedStart[i], facet.Index.ByteStart)
+				assert.Equal(t, tt.expectedEnd[i], facet.Index.ByteEnd)
+			}
+		})
+	}
+}
+
+func TestDetectMentions_NilResolver(t *testing.T) {
+	assert.Empty(t, DetectMentions("Hi @alice.bsky.social", nil))
+}
@@ -2,11 +2,18 @@ package atproto
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/think-root/bluesky-connector/internal/logger"
 	"github.com/think-root/bluesky-connector/internal/models"
 )
 
@@ -14,29 +21,100 @@ const (
 	DefaultBaseURL = "https://bsky.social"
 	CreateSessionEndpoint = "/xrpc/com.atproto.server.createSession"
 	RefreshSessionEndpoint = "/xrpc/com.atproto.server.refreshSession"
+	GetSessionEndpoint = "/xrpc/com.atproto.server.getSession"
+	GetServiceAuthEndpoint = "/xrpc/com.atproto.server.getServiceAuth"
 )
 
 type SessionManager struct {
 	baseURL     string
 	httpClient  *http.Client
-	accessToken string
+	rateLimiter *RateLimiter
+
+	// mu guards the session. refreshing is the refresh in flight, shared by
+	// every caller needing one, and refreshTimer refreshes the access token
+	// ahead of its expiry
+	mu           sync.Mutex
+	accessToken  string
 	refreshToken string
+	accessExpiry time.Time
+	refreshing   *refreshCall
+	refreshTimer *time.Timer
+
+	// identifier and password log in again when the refresh token itself
+	// is rejected
+	identifier string
+	password   string
+
+	// onRefresh is called with the new tokens after every refresh
+	onRefresh func(*models.CreateSessionResponse)
+}
+
+type refreshCall struct {
+	done    chan struct{}
+	session *models.CreateSessionResponse
+	err     error
 }
 
+const (
+	// RequestTimeout bounds every request of a session and the managers
+	// built on it, including any wait for an exhausted rate limit
+	RequestTimeout = 30 * time.Second
+
+	// refreshAhead is how long before the access token expires it is
+	// refreshed in the background
+	refreshAhead = 5 * time.Minute
+
+	// expiryLeeway is how close to expiry an access token is refreshed
+	// before use rather than sent to fail with ExpiredToken
+	expiryLeeway = 30 * time.Second
+)
+
 func NewSessionManager(baseURL string) *SessionManager {
 	if baseURL == "" {
 		baseURL = DefaultBaseURL
 	}
 	
+	rateLimiter := NewRateLimiter(DefaultRateLimitMaxWait)
+
 	return &SessionManager{
 		baseURL: baseURL,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   RequestTimeout,
+			Transport: rateLimiter,
 		},
+		rateLimiter: rateLimiter,
 	}
 }
 
+// UseRateLimiter makes every request of the session and the managers built
+// on it go through rl, so budgets outlive the session
+func (sm *SessionManager) UseRateLimiter(rl *RateLimiter) {
+	sm.rateLimiter = rl
+	sm.httpClient.Transport = rl
+}
+
+func (sm *SessionManager) RateLimiter() *RateLimiter {
+	return sm.rateLimiter
+}
+
+// CreateSession logs in and remembers the credentials, to log in again should
+// the session ever be revoked
 func (sm *SessionManager) CreateSession(identifier, password string) (*models.CreateSessionResponse, error) {
+	sessionResp, err := sm.createSession(identifier, password)
+	if err != nil {
+		return nil, err
+	}
+
+	sm.mu.Lock()
+	sm.identifier = identifier
+	sm.password = password
+	sm.setSessionLocked(sessionResp.AccessJWT, sessionResp.RefreshJWT)
+	sm.mu.Unlock()
+
+	return sessionResp, nil
+}
+
+func (sm *SessionManager) createSession(identifier, password string) (*models.CreateSessionResponse, error) {
 	reqBody := models.CreateSessionRequest{
 		Identifier: identifier,
 		Password:   password,
@@ -73,26 +151,69 @@ func (sm *SessionManager) CreateSession(identifier, password string) (*models.Cr
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	// Store tokens for future use
-	sm.accessToken = sessionResp.AccessJWT
-	sm.refreshToken = sessionResp.RefreshJWT
-
 	return &sessionResp, nil
 }
 
+// RefreshSession exchanges the refresh token for new tokens. Concurrent calls
+// wait for and share a single refresh. When the refresh token is expired or
+// revoked, it logs in again with the credentials of CreateSession or
+// SetCredentials
 func (sm *SessionManager) RefreshSession() (*models.CreateSessionResponse, error) {
-	if sm.refreshToken == "" {
+	sm.mu.Lock()
+	if call := sm.refreshing; call != nil {
+		sm.mu.Unlock()
+		<-call.done
+		return call.session, call.err
+	}
+
+	call := &refreshCall{done: make(chan struct{})}
+	sm.refreshing = call
+	refreshToken, identifier, password := sm.refreshToken, sm.identifier, sm.password
+	sm.mu.Unlock()
+
+	call.session, call.err = sm.refreshSession(refreshToken)
+
+	var xrpcErr *XRPCError
+	if call.err != nil && identifier != "" && (refreshToken == "" || errors.As(call.err, &xrpcErr) && refreshTokenRejected(xrpcErr)) {
+		logger.Debugf("Refresh token rejected (%v), logging in again...", call.err)
+		call.session, call.err = sm.createSession(identifier, password)
+	}
+
+	sm.mu.Lock()
+	sm.refreshing = nil
+	if call.err == nil {
+		sm.setSessionLocked(call.session.AccessJWT, call.session.RefreshJWT)
+	}
+	onRefresh := sm.onRefresh
+	sm.mu.Unlock()
+	close(call.done)
+
+	if call.err != nil {
+		return nil, call.err
+	}
+
+	logger.Debug("Session refreshed successfully")
+
+	if onRefresh != nil {
+		onRefresh(call.session)
+	}
+
+	return call.session, nil
+}
+
+func (sm *SessionManager) refreshSession(refreshToken string) (*models.CreateSessionResponse, error) {
+	if refreshToken == "" {
 		return nil, fmt.Errorf("no refresh token available")
 	}
 
-	fmt.Printf("DEBUG: Refreshing session with refresh token: %s...\n", sm.refreshToken[:20])
+	logger.Debug("Refreshing session...")
 
 	req, err := http.NewRequest("POST", sm.baseURL+RefreshSessionEndpoint, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+sm.refreshToken)
+	req.Header.Set("Authorization", "Bearer "+refreshToken)
 
 	resp, err := sm.httpClient.Do(req)
 	if err != nil {
@@ -101,13 +222,9 @@ func (sm *SessionManager) RefreshSession() (*models.CreateSessionResponse, error
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		var atError models.ATProtoError
-		if err := json.NewDecoder(resp.Body).Decode(&atError); err == nil {
-			fmt.Printf("DEBUG: Refresh session failed: %s\n", atError.String())
-			return nil, fmt.Errorf("AT Protocol error: %s", atError.String())
-		}
-		fmt.Printf("DEBUG: Refresh session HTTP error: %d\n", resp.StatusCode)
-		return nil, fmt.Errorf("HTTP error: %d", resp.StatusCode)
+		xrpcErr := newXRPCError(resp)
+		logger.Debugf("Refresh session failed: %v", xrpcErr)
+		return nil, xrpcErr
 	}
 
 	var sessionResp models.CreateSessionResponse
@@ -115,21 +232,210 @@ func (sm *SessionManager) RefreshSession() (*models.CreateSessionResponse, error
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	// Update tokens
-	oldAccessToken := sm.accessToken[:20]
-	sm.accessToken = sessionResp.AccessJWT
-	sm.refreshToken = sessionResp.RefreshJWT
+	return &sessionResp, nil
+}
+
+// refreshTokenRejected reports whether a failed refresh means the refresh
+// token can no longer be used, as opposed to the PDS failing
+func refreshTokenRejected(err *XRPCError) bool {
+	switch err.Name {
+	case "ExpiredToken", "InvalidToken", "AuthenticationRequired", "AccountTakedown":
+		return true
+	}
+	return err.StatusCode == http.StatusUnauthorized
+}
+
+// setSessionLocked stores new tokens and schedules the refresh of the access
+// token ahead of its expiry. sm.mu must be held
+func (sm *SessionManager) setSessionLocked(accessToken, refreshToken string) {
+	sm.accessToken = accessToken
+	sm.refreshToken = refreshToken
+	sm.accessExpiry, _ = tokenExpiry(accessToken)
+
+	if sm.refreshTimer != nil {
+		sm.refreshTimer.Stop()
+		sm.refreshTimer = nil
+	}
+	if sm.accessExpiry.IsZero() || refreshToken == "" {
+		return
+	}
+
+	sm.refreshTimer = time.AfterFunc(max(time.Until(sm.accessExpiry)-refreshAhead, 0), func() {
+		if _, err := sm.RefreshSession(); err != nil {
+			logger.Warnf("Background session refresh failed: %v", err)
+		}
+	})
+}
+
+// tokenExpiry reads the exp claim of a JWT without verifying it; the PDS does
+// that. It reports false for tokens that are not JWTs or carry no exp
+func tokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+
+	return time.Unix(claims.Exp, 0), true
+}
+
+// SetCredentials sets what RefreshSession logs in with when the refresh
+// token is rejected, for sessions restored with ResumeSession
+func (sm *SessionManager) SetCredentials(identifier, password string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.identifier = identifier
+	sm.password = password
+}
+
+// OnRefresh registers fn to be called with the new tokens whenever the
+// session is refreshed
+func (sm *SessionManager) OnRefresh(fn func(*models.CreateSessionResponse)) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.onRefresh = fn
+}
+
+// ResumeSession restores the tokens of a session created earlier. Check it
+// with GetSession before relying on it
+func (sm *SessionManager) ResumeSession(accessToken, refreshToken string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.setSessionLocked(accessToken, refreshToken)
+}
+
+// ClearSession forgets the tokens and stops refreshing them, so the session
+// has to be created again
+func (sm *SessionManager) ClearSession() {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.setSessionLocked("", "")
+}
+
+// GetSession returns the account the access token belongs to, failing with
+// an *XRPCError when the PDS no longer accepts it
+func (sm *SessionManager) GetSession() (*models.GetSessionResponse, error) {
+	if !sm.IsAuthenticated() {
+		return nil, fmt.Errorf("not authenticated")
+	}
+
+	req, err := http.NewRequest("GET", sm.baseURL+GetSessionEndpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+sm.GetAccessToken())
+
+	resp, err := sm.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newXRPCError(resp)
+	}
 
-	fmt.Printf("DEBUG: Session refreshed successfully. Old token: %s..., New token: %s...\n",
-		oldAccessToken, sm.accessToken[:20])
+	var sessionResp models.GetSessionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&sessionResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
 
 	return &sessionResp, nil
 }
 
+// GetServiceAuth asks the PDS for a short-lived token that lets another
+// service (aud) call the lxm method on behalf of the user
+func (sm *SessionManager) GetServiceAuth(aud, lxm string, expiresIn time.Duration) (string, error) {
+	if !sm.IsAuthenticated() {
+		return "", fmt.Errorf("not authenticated")
+	}
+
+	params := url.Values{}
+	params.Set("aud", aud)
+	params.Set("lxm", lxm)
+	params.Set("exp", strconv.FormatInt(time.Now().Add(expiresIn).Unix(), 10))
+
+	req, err := http.NewRequest("GET", sm.baseURL+GetServiceAuthEndpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+sm.GetAccessToken())
+
+	resp, err := sm.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var atError models.ATProtoError
+		if err := json.NewDecoder(resp.Body).Decode(&atError); err == nil {
+			return "", fmt.Errorf("AT Protocol error: %s", atError.String())
+		}
+		return "", fmt.Errorf("HTTP error: %d", resp.StatusCode)
+	}
+
+	var authResp models.ServiceAuthResponse
+	if err := json.NewDecoder(resp.Body).Decode(&authResp); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return authResp.Token, nil
+}
+
+// ServiceDID returns the did:web identifier of the PDS this session talks to,
+// which is the audience other services use when acting on its behalf
+func (sm *SessionManager) ServiceDID() string {
+	u, err := url.Parse(sm.baseURL)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	return "did:web:" + u.Hostname()
+}
+
+// GetAccessToken returns the access token to call the PDS with, refreshing
+// it first when it is about to expire
 func (sm *SessionManager) GetAccessToken() string {
-	return sm.accessToken
+	sm.mu.Lock()
+	token, expiry := sm.accessToken, sm.accessExpiry
+	sm.mu.Unlock()
+
+	if token != "" && !expiry.IsZero() && time.Until(expiry) < expiryLeeway {
+		if session, err := sm.RefreshSession(); err == nil {
+			return session.AccessJWT
+		}
+	}
+
+	return token
+}
+
+func (sm *SessionManager) GetRefreshToken() string {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	return sm.refreshToken
 }
 
 func (sm *SessionManager) IsAuthenticated() bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
 	return sm.accessToken != ""
 }
\ No newline at end of file
@@ -0,0 +1,152 @@
+package atproto
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/think-root/bluesky-connector/internal/models"
+)
+
+// testJWT builds an unsigned JWT expiring at exp, marked with id
+func testJWT(id string, exp time.Time) string {
+	payload, _ := json.Marshal(map[string]any{"sub": "did:plc:me", "jti": id, "exp": exp.Unix()})
+	return "eyJhbGciOiJFUzI1NksifQ." + base64.RawURLEncoding.EncodeToString(payload) + ".c2ln"
+}
+
+func TestTokenExpiry(t *testing.T) {
+	exp := time.Unix(1767225600, 0)
+
+	got, ok := tokenExpiry(testJWT("a", exp))
+	assert.True(t, ok)
+	assert.True(t, exp.Equal(got))
+
+	_, ok = tokenExpiry("token")
+	assert.False(t, ok)
+	_, ok = tokenExpiry("a.!!!.c")
+	assert.False(t, ok)
+}
+
+func TestSessionManager_ConcurrentRefreshIsShared(t *testing.T) {
+	var refreshes atomic.Int32
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, RefreshSessionEndpoint, r.URL.Path)
+		n := refreshes.Add(1)
+		<-release
+		json.NewEncoder(w).Encode(map[string]string{
+			"accessJwt":  fmt.Sprintf("access-%d", n),
+			"refreshJwt": fmt.Sprintf("refresh-%d", n),
+		})
+	}))
+	defer server.Close()
+
+	sm := NewSessionManager(server.URL)
+	sm.ResumeSession("access-0", "refresh-0")
+
+	var refreshed atomic.Int32
+	sm.OnRefresh(func(session *models.CreateSessionResponse) {
+		refreshed.Add(1)
+	})
+
+	var wg sync.WaitGroup
+	tokens := make([]string, 5)
+	for i := range tokens {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			session, err := sm.RefreshSession()
+			if assert.NoError(t, err) {
+				tokens[i] = session.AccessJWT
+			}
+		}()
+	}
+
+	require.Eventually(t, func() bool { return refreshes.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), refreshes.Load())
+	assert.Equal(t, int32(1), refreshed.Load())
+	for _, token := range tokens {
+		assert.Equal(t, "access-1", token)
+	}
+	assert.Equal(t, "refresh-1", sm.GetRefreshToken())
+}
+
+func TestSessionManager_RejectedRefreshTokenLogsInAgain(t *testing.T) {
+	var logins atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case RefreshSessionEndpoint:
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "ExpiredToken", "message": "Token has been revoked"})
+		case CreateSessionEndpoint:
+			logins.Add(1)
+			var req models.CreateSessionRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, "alice.bsky.social", req.Identifier)
+			json.NewEncoder(w).Encode(map[string]string{"accessJwt": "new-access", "refreshJwt": "new-refresh"})
+		}
+	}))
+	defer server.Close()
+
+	sm := NewSessionManager(server.URL)
+	sm.ResumeSession("old-access", "old-refresh")
+
+	// Without credentials the refresh failure is returned
+	_, err := sm.RefreshSession()
+	var xrpcErr *XRPCError
+	require.ErrorAs(t, err, &xrpcErr)
+	assert.Equal(t, "ExpiredToken", xrpcErr.Name)
+	assert.Equal(t, int32(0), logins.Load())
+
+	sm.SetCredentials("alice.bsky.social", "app-password")
+	session, err := sm.RefreshSession()
+	require.NoError(t, err)
+	assert.Equal(t, "new-access", session.AccessJWT)
+	assert.Equal(t, "new-access", sm.GetAccessToken())
+	assert.Equal(t, int32(1), logins.Load())
+}
+
+func TestSessionManager_RefreshesAheadOfExpiry(t *testing.T) {
+	fresh := testJWT("fresh", time.Now().Add(2*time.Hour))
+
+	var refreshes atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		refreshes.Add(1)
+		json.NewEncoder(w).Encode(map[string]string{"accessJwt": fresh, "refreshJwt": "refresh-2"})
+	}))
+	defer server.Close()
+
+	// A token expiring within refreshAhead is refreshed in the background
+	sm := NewSessionManager(server.URL)
+	sm.ResumeSession(testJWT("expiring", time.Now().Add(time.Minute)), "refresh-1")
+	defer sm.ClearSession()
+
+	require.Eventually(t, func() bool { return sm.GetRefreshToken() == "refresh-2" }, time.Second, time.Millisecond)
+	assert.Equal(t, fresh, sm.GetAccessToken())
+	assert.Equal(t, int32(1), refreshes.Load())
+
+	// An already expired token is refreshed before it is handed out
+	sm = NewSessionManager(server.URL)
+	sm.mu.Lock()
+	sm.accessToken, sm.refreshToken = "expired", "refresh-1"
+	sm.accessExpiry = time.Now().Add(-time.Minute)
+	sm.mu.Unlock()
+	defer sm.ClearSession()
+
+	assert.Equal(t, fresh, sm.GetAccessToken())
+	assert.Equal(t, int32(2), refreshes.Load())
+}
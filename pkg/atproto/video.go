@@ -0,0 +1,249 @@
+package atproto
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/think-root/bluesky-connector/internal/logger"
+	"github.com/think-root/bluesky-connector/internal/models"
+)
+
+const (
+	DefaultVideoServiceURL = "https://video.bsky.app"
+	UploadVideoEndpoint    = "/xrpc/app.bsky.video.uploadVideo"
+	GetJobStatusEndpoint   = "/xrpc/app.bsky.video.getJobStatus"
+
+	JobStateCompleted = "JOB_STATE_COMPLETED"
+	JobStateFailed    = "JOB_STATE_FAILED"
+
+	// MaxVideoSize is the largest video the video service accepts
+	MaxVideoSize = 100 * 1024 * 1024
+)
+
+type VideoManager struct {
+	baseURL        string
+	audience       string
+	httpClient     *http.Client
+	sessionManager *SessionManager
+	mediaManager   *MediaManager
+	pollInterval   time.Duration
+	jobTimeout     time.Duration
+}
+
+// NewVideoManager creates a manager that uploads videos through the video
+// service at baseURL. audience is the DID the service auth token is issued
+// for and defaults to the did:web of the session's PDS
+func NewVideoManager(baseURL, audience string, sessionManager *SessionManager, mediaManager *MediaManager) *VideoManager {
+	if baseURL == "" {
+		baseURL = DefaultVideoServiceURL
+	}
+
+	return &VideoManager{
+		baseURL:  baseURL,
+		audience: audience,
+		// Uploads of up to 100MB need far longer than the default API timeout
+		httpClient: &http.Client{
+			Timeout:   5 * time.Minute,
+			Transport: sessionManager.RateLimiter(),
+		},
+		sessionManager: sessionManager,
+		mediaManager:   mediaManager,
+		pollInterval:   2 * time.Second,
+		jobTimeout:     5 * time.Minute,
+	}
+}
+
+// UploadVideo uploads a video to the video service and waits until it has been
+// processed, returning the blob to reference from an app.bsky.embed.video
+func (vm *VideoManager) UploadVideo(did string, data []byte, mimeType string) (*models.BlobRef, error) {
+	if len(data) > MaxVideoSize {
+		return nil, fmt.Errorf("video too large: %d bytes (max %d)", len(data), MaxVideoSize)
+	}
+
+	audience := vm.audience
+	if audience == "" {
+		audience = vm.sessionManager.ServiceDID()
+	}
+
+	// The video service uploads the processed blob to the PDS on our behalf
+	token, err := vm.sessionManager.GetServiceAuth(audience, "com.atproto.repo.uploadBlob", 30*time.Minute)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service auth: %w", err)
+	}
+
+	status, err := vm.startUpload(did, token, data, mimeType)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debugf("Video upload accepted, job %s in state %s", status.JobID, status.State)
+
+	return vm.waitForJob(status)
+}
+
+func (vm *VideoManager) startUpload(did, token string, data []byte, mimeType string) (*models.VideoJobStatus, error) {
+	params := url.Values{}
+	params.Set("did", did)
+	params.Set("name", fmt.Sprintf("%d.mp4", time.Now().UnixNano()))
+
+	req, err := http.NewRequest("POST", vm.baseURL+UploadVideoEndpoint+"?"+params.Encode(), bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", mimeType)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := vm.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	// The service answers with the job status either at the top level or
+	// wrapped in jobStatus, and with 409 when the same video was already
+	// uploaded, in which case the existing job can be reused
+	var uploadResp struct {
+		models.VideoJobStatus
+		JobStatus *models.VideoJobStatus `json:"jobStatus"`
+	}
+	if err := json.Unmarshal(body, &uploadResp); err != nil {
+		return nil, fmt.Errorf("HTTP error: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	status := &uploadResp.VideoJobStatus
+	if uploadResp.JobStatus != nil {
+		status = uploadResp.JobStatus
+	}
+
+	if resp.StatusCode != http.StatusOK && !(resp.StatusCode == http.StatusConflict && status.JobID != "") {
+		var atError models.ATProtoError
+		if err := json.Unmarshal(body, &atError); err == nil && atError.Error != "" {
+			return nil, fmt.Errorf("AT Protocol error: %s", atError.String())
+		}
+		return nil, fmt.Errorf("HTTP error: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	if status.JobID == "" {
+		return nil, fmt.Errorf("video service did not return a job ID")
+	}
+
+	return status, nil
+}
+
+// waitForJob polls the job status until the video is processed or fails
+func (vm *VideoManager) waitForJob(status *models.VideoJobStatus) (*models.BlobRef, error) {
+	deadline := time.Now().Add(vm.jobTimeout)
+
+	for {
+		switch {
+		case status.Blob != nil:
+			if status.Blob.Type == "" {
+				status.Blob.Type = "blob"
+			}
+			return status.Blob, nil
+		case status.State == JobStateFailed:
+			return nil, fmt.Errorf("video processing failed: %s %s", status.Error, status.Message)
+		case status.State == JobStateCompleted:
+			return nil, fmt.Errorf("video processing completed without a blob")
+		case time.Now().After(deadline):
+			return nil, fmt.Errorf("timed out waiting for video job %s (state %s)", status.JobID, status.State)
+		}
+
+		time.Sleep(vm.pollInterval)
+
+		next, err := vm.GetJobStatus(status.JobID)
+		if err != nil {
+			return nil, err
+		}
+		logger.Debugf("Video job %s state %s, progress %d%%", next.JobID, next.State, next.Progress)
+		status = next
+	}
+}
+
+// GetJobStatus returns the processing state of an uploaded video
+func (vm *VideoManager) GetJobStatus(jobID string) (*models.VideoJobStatus, error) {
+	req, err := http.NewRequest("GET", vm.baseURL+GetJobStatusEndpoint+"?jobId="+url.QueryEscape(jobID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := vm.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var atError models.ATProtoError
+		if err := json.NewDecoder(resp.Body).Decode(&atError); err == nil {
+			return nil, fmt.Errorf("AT Protocol error: %s", atError.String())
+		}
+		return nil, fmt.Errorf("HTTP error: %d", resp.StatusCode)
+	}
+
+	var statusResp models.GetJobStatusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&statusResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &statusResp.JobStatus, nil
+}
+
+// CreateVideoEmbed uploads a video and its caption tracks and returns an
+// app.bsky.embed.video embed referencing them
+func (vm *VideoManager) CreateVideoEmbed(did string, video models.VideoUpload) (*models.Embed, error) {
+	mimeType := DetectMimeType(video.Data)
+
+	blobRef, err := vm.UploadVideo(did, video.Data, mimeType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload video: %w", err)
+	}
+
+	embed := &models.Embed{
+		Type:  "app.bsky.embed.video",
+		Video: blobRef,
+		Alt:   video.Alt,
+	}
+
+	for _, caption := range video.Captions {
+		captionRef, err := vm.mediaManager.UploadBlob(caption.Data, "text/vtt")
+		if err != nil {
+			return nil, fmt.Errorf("failed to upload %s captions: %w", caption.Lang, err)
+		}
+		if captionRef.Type == "" {
+			captionRef.Type = "blob"
+		}
+		embed.Captions = append(embed.Captions, models.EmbedCaption{
+			Lang: caption.Lang,
+			File: captionRef,
+		})
+	}
+
+	return embed, nil
+}
+
+// PreviewVideoEmbed describes a video the way CreateVideoEmbed would embed it,
+// without uploading it or its captions, so the blob references are left empty
+func PreviewVideoEmbed(video models.VideoUpload) *models.Embed {
+	embed := &models.Embed{
+		Type: "app.bsky.embed.video",
+		Alt:  video.Alt,
+	}
+
+	for _, caption := range video.Captions {
+		embed.Captions = append(embed.Captions, models.EmbedCaption{Lang: caption.Lang})
+	}
+
+	return embed
+}
@@ -0,0 +1,91 @@
+package atproto
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVideoManager_UploadVideo(t *testing.T) {
+	var polls int32
+
+	pds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, GetServiceAuthEndpoint, r.URL.Path)
+		assert.Equal(t, "Bearer access-token", r.Header.Get("Authorization"))
+		assert.Equal(t, "did:web:pds.example", r.URL.Query().Get("aud"))
+		assert.Equal(t, "com.atproto.repo.uploadBlob", r.URL.Query().Get("lxm"))
+		json.NewEncoder(w).Encode(map[string]string{"token": "service-token"})
+	}))
+	defer pds.Close()
+
+	videoService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case UploadVideoEndpoint:
+			assert.Equal(t, "Bearer service-token", r.Header.Get("Authorization"))
+			assert.Equal(t, "did:plc:test", r.URL.Query().Get("did"))
+			assert.Equal(t, "video/mp4", r.Header.Get("Content-Type"))
+			json.NewEncoder(w).Encode(map[string]any{
+				"jobId": "job-1",
+				"did":   "did:plc:test",
+				"state": "JOB_STATE_ENCODING",
+			})
+		case GetJobStatusEndpoint:
+			assert.Equal(t, "job-1", r.URL.Query().Get("jobId"))
+			status := map[string]any{"jobId": "job-1", "state": "JOB_STATE_ENCODING", "progress": 50}
+			if atomic.AddInt32(&polls, 1) > 1 {
+				status["state"] = JobStateCompleted
+				status["blob"] = map[string]any{
+					"$type":    "blob",
+					"ref":      map[string]string{"$link": "bafkreivideo"},
+					"mimeType": "video/mp4",
+					"size":     1234,
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]any{"jobStatus": status})
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	}))
+	defer videoService.Close()
+
+	sm := NewSessionManager(pds.URL)
+	sm.accessToken = "access-token"
+
+	vm := NewVideoManager(videoService.URL, "did:web:pds.example", sm, NewMediaManager(pds.URL, sm))
+	vm.pollInterval = time.Millisecond
+
+	blob, err := vm.UploadVideo("did:plc:test", []byte("\x00\x00\x00\x18ftypmp42"), "video/mp4")
+	assert.NoError(t, err)
+	assert.Equal(t, "bafkreivideo", blob.GetRefString())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&polls))
+}
+
+func TestVideoManager_UploadVideoFailedJob(t *testing.T) {
+	pds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"token": "service-token"})
+	}))
+	defer pds.Close()
+
+	videoService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"jobId":   "job-2",
+			"state":   JobStateFailed,
+			"error":   "InvalidVideo",
+			"message": "unsupported codec",
+		})
+	}))
+	defer videoService.Close()
+
+	sm := NewSessionManager(pds.URL)
+	sm.accessToken = "access-token"
+
+	vm := NewVideoManager(videoService.URL, "", sm, NewMediaManager(pds.URL, sm))
+
+	_, err := vm.UploadVideo("did:plc:test", []byte("video"), "video/mp4")
+	assert.ErrorContains(t, err, "unsupported codec")
+}
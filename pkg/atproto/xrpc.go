@@ -0,0 +1,62 @@
+package atproto
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// XRPCError is a failed XRPC call, carrying the HTTP status and the AT
+// Protocol error name so callers can react to specific failures. A 429
+// unwraps to a *RateLimitError
+type XRPCError struct {
+	StatusCode int
+	Name       string
+	Message    string
+
+	rateLimit *RateLimitError
+}
+
+func (e *XRPCError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("HTTP error: %d", e.StatusCode)
+	}
+	return fmt.Sprintf("AT Protocol error: %s: %s", e.Name, e.Message)
+}
+
+func (e *XRPCError) Unwrap() error {
+	if e.rateLimit == nil {
+		return nil
+	}
+	return e.rateLimit
+}
+
+// newXRPCError reads the error body of a non-200 XRPC response
+func newXRPCError(resp *http.Response) *XRPCError {
+	xrpcErr := &XRPCError{StatusCode: resp.StatusCode}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var atError struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &atError); err == nil {
+		xrpcErr.Name = atError.Error
+		xrpcErr.Message = atError.Message
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if !ok {
+			wait = defaultRetryAfter
+		}
+		xrpcErr.rateLimit = &RateLimitError{RetryAfter: wait}
+		if resp.Request != nil {
+			xrpcErr.rateLimit.Host = resp.Request.URL.Host
+		}
+	}
+
+	return xrpcErr
+}